@@ -0,0 +1,457 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/spf13/viper"
+)
+
+// computedTag - virtual tag whose value is calculated from the record's tags
+type computedTag struct {
+	name string
+	src  string
+	expr expr
+}
+
+// expr - node of computed tag's expression
+type expr interface {
+	eval(m map[string]interface{}) (interface{}, bool)
+}
+
+type (
+	exprConst struct{ v interface{} }
+	exprTag   struct{ name string }
+	exprNeg   struct{ x expr }
+	exprOp    struct {
+		op   byte
+		l, r expr
+	}
+	exprCall struct {
+		fn   string
+		args []expr
+		re   *regexp.Regexp
+	}
+)
+
+// exprFuncs - functions which may be used in expressions and number of their arguments
+var exprFuncs = map[string]int{
+	"lower":  1,
+	"upper":  1,
+	"re":     2,
+	"second": 1,
+	"minute": 1,
+	"hour":   1,
+	"day":    1,
+}
+
+// bucketLayouts - layouts of time buckets functions
+var bucketLayouts = map[string]struct {
+	d      time.Duration
+	layout string
+}{
+	"second": {time.Second, "2006-01-02T15:04:05"},
+	"minute": {time.Minute, "2006-01-02T15:04"},
+	"hour":   {time.Hour, "2006-01-02T15"},
+	"day":    {24 * time.Hour, "2006-01-02"},
+}
+
+func (e exprConst) eval(map[string]interface{}) (interface{}, bool) {
+	return e.v, true
+}
+
+func (e exprTag) eval(m map[string]interface{}) (interface{}, bool) {
+	v, ok := lookupTag(m, e.name)
+	return v, ok && v != nil
+}
+
+func (e exprNeg) eval(m map[string]interface{}) (interface{}, bool) {
+	v, ok := e.x.eval(m)
+	if !ok {
+		return nil, false
+	}
+	n, ok := toNumber(v)
+	return -n, ok
+}
+
+func (e exprOp) eval(m map[string]interface{}) (interface{}, bool) {
+	l, ok := e.l.eval(m)
+	if !ok {
+		return nil, false
+	}
+	r, ok := e.r.eval(m)
+	if !ok {
+		return nil, false
+	}
+	ln, lok := toNumber(l)
+	rn, rok := toNumber(r)
+	if !lok || !rok {
+		lt, lok := parseTime(l)
+		rt, rok := parseTime(r)
+		switch {
+		case lok && rok && e.op == '-':
+			// difference of times is in milliseconds
+			return float64(lt.Sub(rt)) / float64(time.Millisecond), true
+		case e.op == '+':
+			return tagToString(l) + tagToString(r), true
+		}
+		return nil, false
+	}
+	switch e.op {
+	case '+':
+		return ln + rn, true
+	case '-':
+		return ln - rn, true
+	case '*':
+		return ln * rn, true
+	case '/':
+		if rn == 0 {
+			return nil, false
+		}
+		return ln / rn, true
+	}
+	return nil, false
+}
+
+func (e exprCall) eval(m map[string]interface{}) (interface{}, bool) {
+	v, ok := e.args[0].eval(m)
+	if !ok {
+		return nil, false
+	}
+	switch e.fn {
+	case "lower":
+		return strings.ToLower(tagToString(v)), true
+	case "upper":
+		return strings.ToUpper(tagToString(v)), true
+	case "re":
+		sm := e.re.FindStringSubmatch(tagToString(v))
+		switch {
+		case sm == nil:
+			return nil, false
+		case len(sm) > 1:
+			return sm[1], true
+		}
+		return sm[0], true
+	}
+	t, ok := parseTimestamp(v)
+	if !ok {
+		return nil, false
+	}
+	b := bucketLayouts[e.fn]
+	return t.Truncate(b.d).Format(b.layout), true
+}
+
+// exprParser - recursive descent parser of expressions:
+//
+//	expr = term {(+|-) term}; term = factor {(*|/) factor};
+//	factor = -factor | number | "string" | tag | `tag` | func(expr, ...) | (expr);
+//
+//	strings may contain \" (\' and \\), other escapes are kept as is (for regexps);
+//	tags with other chars than letters, digits, _, . and @ (e.g. x-request-id) should be quoted with backticks
+type exprParser struct {
+	s   string
+	pos int
+}
+
+// parseExpr parses expression of computed tag
+func parseExpr(s string) (expr, error) {
+	p := &exprParser{s: s}
+	e, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.s) {
+		return nil, fmt.Errorf("unexpected %q at %d", p.s[p.pos:], p.pos+1)
+	}
+	return e, nil
+}
+
+func (p *exprParser) skipSpaces() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// next returns next not space char (0 at the end)
+func (p *exprParser) next() byte {
+	p.skipSpaces()
+	if p.pos == len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *exprParser) expr() (expr, error) {
+	l, err := p.term()
+	for err == nil {
+		op := p.next()
+		if op != '+' && op != '-' {
+			break
+		}
+		p.pos++
+		var r expr
+		if r, err = p.term(); err == nil {
+			l = exprOp{op: op, l: l, r: r}
+		}
+	}
+	return l, err
+}
+
+func (p *exprParser) term() (expr, error) {
+	l, err := p.factor()
+	for err == nil {
+		op := p.next()
+		if op != '*' && op != '/' {
+			break
+		}
+		p.pos++
+		var r expr
+		if r, err = p.factor(); err == nil {
+			l = exprOp{op: op, l: l, r: r}
+		}
+	}
+	return l, err
+}
+
+func (p *exprParser) factor() (expr, error) {
+	c := p.next()
+	switch {
+	case c == 0:
+		return nil, errors.New("unexpected end of expression")
+	case c == '-':
+		p.pos++
+		x, err := p.factor()
+		return exprNeg{x: x}, err
+	case c == '(':
+		p.pos++
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ')' {
+			return nil, fmt.Errorf("missing ) at %d", p.pos+1)
+		}
+		p.pos++
+		return e, nil
+	case c == '"' || c == '\'':
+		s, err := p.quoted(c)
+		return exprConst{v: s}, err
+	case c == '`':
+		name, err := p.quoted(c)
+		if err == nil && name == "" {
+			err = fmt.Errorf("empty tag name at %d", p.pos)
+		}
+		return exprTag{name: name}, err
+	case c >= '0' && c <= '9' || c == '.':
+		from := p.pos
+		for p.pos < len(p.s) && (p.s[p.pos] >= '0' && p.s[p.pos] <= '9' || p.s[p.pos] == '.') {
+			p.pos++
+		}
+		n, err := strconv.ParseFloat(p.s[from:p.pos], 64)
+		return exprConst{v: n}, err
+	}
+	from := p.pos
+	for p.pos < len(p.s) && isTagChar(rune(p.s[p.pos])) {
+		p.pos++
+	}
+	if from == p.pos {
+		return nil, fmt.Errorf("unexpected %q at %d", p.s[p.pos:], p.pos+1)
+	}
+	name := p.s[from:p.pos]
+	if p.next() != '(' {
+		return exprTag{name: name}, nil
+	}
+	return p.call(name)
+}
+
+// quoted returns string quoted by q starting at current position
+func (p *exprParser) quoted(q byte) (string, error) {
+	b := strings.Builder{}
+	for i := p.pos + 1; i < len(p.s); i++ {
+		switch c := p.s[i]; {
+		case c == q:
+			p.pos = i + 1
+			return b.String(), nil
+		case c == '\\' && i+1 < len(p.s) && (p.s[i+1] == q || p.s[i+1] == '\\'):
+			i++
+			b.WriteByte(p.s[i])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if q == '`' {
+		return "", errors.New("unterminated tag name")
+	}
+	return "", errors.New("unterminated string")
+}
+
+func (p *exprParser) call(name string) (expr, error) {
+	argc, ok := exprFuncs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function: %s", name)
+	}
+	p.pos++
+	e := exprCall{fn: name}
+	for p.next() != ')' {
+		if len(e.args) > 0 {
+			if p.next() != ',' {
+				return nil, fmt.Errorf("missing , at %d", p.pos+1)
+			}
+			p.pos++
+		}
+		a, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		e.args = append(e.args, a)
+	}
+	p.pos++
+	if len(e.args) != argc {
+		return nil, fmt.Errorf("%s: %d argument(s) expected", name, argc)
+	}
+	if name == "re" {
+		c, ok := e.args[1].(exprConst)
+		if !ok {
+			return nil, errors.New("re: pattern should be string")
+		}
+		re, err := regexp.Compile(tagToString(c.v))
+		if err != nil {
+			return nil, err
+		}
+		e.re = re
+	}
+	return e, nil
+}
+
+func isTagChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '@'
+}
+
+// SetComputed adds (or replaces) computed tag name; empty src removes it
+func (f *File) SetComputed(name string, src string) error {
+	if !isTagName(name) {
+		return fmt.Errorf("invalid tag name: %s", name)
+	}
+	for i, c := range f.computed {
+		if c.name == name {
+			f.computed = append(f.computed[:i], f.computed[i+1:]...)
+			break
+		}
+	}
+	if src == "" {
+		return nil
+	}
+	e, err := parseExpr(src)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	f.computed = append(f.computed, computedTag{name: name, src: src, expr: e})
+	f.addKnownTag(name)
+	return nil
+}
+
+// ResetComputed removes all the computed tags
+func (f *File) ResetComputed() {
+	f.computed = nil
+}
+
+// Computed returns definitions of computed tags ("name = expr")
+func (f *File) Computed() []string {
+	ret := []string{}
+	for _, c := range f.computed {
+		ret = append(ret, fmt.Sprintf("%s = %s", c.name, c.src))
+	}
+	return ret
+}
+
+// computeTags adds values of computed tags to decoded record (in definition order,
+// so a computed tag may use ones defined before it)
+func (f *File) computeTags(m map[string]interface{}) {
+	if m == nil {
+		return
+	}
+	for _, c := range f.computed {
+		if v, ok := c.expr.eval(m); ok {
+			if n, ok := v.(float64); ok && (math.IsNaN(n) || math.IsInf(n, 0)) {
+				continue
+			}
+			m[c.name] = v
+		}
+	}
+}
+
+func isTagName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !isTagChar(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// computedFromConfig adds computed tags from config option let (list of "<name> = <expression>")
+func (f *File) computedFromConfig() error {
+	for _, l := range viper.GetStringSlice("let") {
+		name, src, err := parseBinding(l)
+		if err != nil {
+			return err
+		}
+		if err = f.SetComputed(name, src); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// letCommandExecute processes :let <name> = <expression> (:let <name> = removes the tag, :let lists tags)
+func letCommandExecute(t *term) {
+	arg := strings.TrimSpace(strings.TrimPrefix(t.command, ":let"))
+	file := t.root.file
+	if arg == "" {
+		defs := file.Computed()
+		if len(defs) == 0 {
+			t.message = "no computed tags"
+			return
+		}
+		t.message = strings.Join(defs, "; ")
+		return
+	}
+	parts := strings.SplitN(arg, "=", 2)
+	if len(parts) != 2 {
+		t.message = "usage: :let <name> = <expression>"
+		return
+	}
+	name, src := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	if err := file.SetComputed(name, src); err != nil {
+		t.message = err.Error()
+		return
+	}
+	t.redraw()
+}
+
+func letCommandOptions(t *term) {
+	if !strings.HasSuffix(t.command, " ") {
+		t.command += " "
+	}
+	names := []string{}
+	for n := range exprFuncs {
+		names = append(names, n+"(")
+	}
+	for _, n := range t.f.KnownTags() {
+		if !isTagName(n) {
+			n = "`" + n + "`"
+		}
+		names = append(names, n)
+	}
+	t.options = newOptionsFromArray(names, false)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestComputedTags(t *testing.T) {
+	resetConfig()
+	f := newTestFile(t, []string{
+		`{"time": "2020-01-01T00:00:01.500", "start": "2020-01-01T00:00:00", "msg": "user=bob said \"hi\"", "x-request-id": "r1", "a": 2, "b": 3}`,
+	})
+	for _, c := range []struct {
+		src      string
+		expected interface{}
+	}{
+		{"a + b * 2", 8.0},
+		{"(a + b) * -2", -10.0},
+		{"time - start", 1500.0},
+		{`re(msg, "user=(\w+)")`, "bob"},
+		{`re(msg, "said \"(\w+)\"")`, "hi"},
+		{"upper(`x-request-id`)", "R1"},
+		{"x-request-id", nil},
+		{"minute(start)", "2020-01-01T00:00"},
+	} {
+		if err := f.SetComputed("c", c.src); err != nil {
+			t.Errorf("%s: %v", c.src, err)
+			continue
+		}
+		if v := f.Line(0)["c"]; v != c.expected {
+			t.Errorf("%s: expected %v, got %v", c.src, c.expected, v)
+		}
+	}
+	for _, src := range []string{`"abc`, "`abc", "``", "a +", "nofunc(a)", "upper(a, b)"} {
+		if err := f.SetComputed("c", src); err == nil {
+			t.Errorf("%s: error expected", src)
+		}
+	}
+}
+
+func TestLetCommand(t *testing.T) {
+	resetConfig()
+	tm, scr := newTestTerm(t, 80, 6, records(10))
+	tm.replay(":let double = n * 2", "\r", ":f/double/10", "\r")
+	expectLines(t, scr, "2020-01-01T00:00:05  warn message 5")
+	tm.replay(":let", "\r")
+	expectStatus(t, scr, "double = n * 2")
+	tm.replay(":fr", "\r", ":let double =", "\r", ":let", "\r")
+	expectStatus(t, scr, "no computed tags")
+}
+
+func TestRestoreResetsComputed(t *testing.T) {
+	resetConfig()
+	tm, _ := newTestTerm(t, 80, 6, records(10))
+	tm.replay(":let a = n + 1", "\r")
+	s := tm.session()
+	tm.replay(":let b = n + 2", "\r")
+	if err := tm.restore(s); err != nil {
+		t.Fatal(err)
+	}
+	if defs := tm.root.file.Computed(); len(defs) != 1 || defs[0] != "a = n + 1" {
+		t.Errorf("restore should replace computed tags, got %v", defs)
+	}
+}
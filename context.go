@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"sort"
+)
+
+// lineSeparator - index entry of the separator row between non-adjacent context groups
+const lineSeparator = -1
+
+const defaultContext = 3
+
+// Context returns number of records shown around every match (0 if context is off)
+func (f *FileView) Context() int {
+	return f.context
+}
+
+// SetContext shows (like grep -C) n preceding and following records of parent view around every match;
+//
+//	n == 0 turns context off
+func (f *FileView) SetContext(n int) error {
+	if f.parent == nil {
+		return errors.New("context is available for filtered views only")
+	}
+	if f.unordered {
+		return errors.New("context is not available for reordered views")
+	}
+	if n < 0 {
+		n = 0
+	}
+	if f.matches == nil {
+		f.matches = f.index
+	}
+	f.context = n
+	f.step.Context = n
+	if n == 0 {
+		f.index = f.matches
+		f.matches = nil
+		return nil
+	}
+	p := f.parent
+	index := make([]int, 0, len(f.matches)*(2*n+1))
+	last := -1
+	for _, ln := range f.matches {
+		pos, ok := p.ViewLine(ln)
+		if !ok {
+			continue
+		}
+		from := pos - n
+		if from <= last {
+			from = last + 1
+		} else if last != -1 && from > last+1 {
+			index = append(index, lineSeparator)
+		}
+		if from < 0 {
+			from = 0
+		}
+		for i := from; i <= pos+n && i < p.LinesCount(); i++ {
+			if l := p.getIndex(i); l != lineSeparator {
+				index = append(index, l)
+			}
+			last = i
+		}
+	}
+	f.index = index
+	return nil
+}
+
+// IsContext reports whether view line idx is a context line (or separator) but not a match
+func (f *FileView) IsContext(idx int) bool {
+	if f.matches == nil || idx < 0 || idx >= len(f.index) {
+		return false
+	}
+	ln := f.index[idx]
+	if ln == lineSeparator {
+		return true
+	}
+	i := sort.SearchInts(f.matches, ln)
+	return i == len(f.matches) || f.matches[i] != ln
+}
+
+// IsSeparator reports whether view line idx is a separator between context groups
+func (f *FileView) IsSeparator(idx int) bool {
+	return f.matches != nil && idx >= 0 && idx < len(f.index) && f.index[idx] == lineSeparator
+}
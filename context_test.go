@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestContext(t *testing.T) {
+	resetConfig()
+	tm, scr := newTestTerm(t, 80, 12, records(20))
+	tm.replay(":f/n/^(5|6|14|16)$/$", "\r", ":fc1", "\r")
+	expectLines(t, scr,
+		"2020-01-01T00:00:04",
+		"2020-01-01T00:00:05",
+		"2020-01-01T00:00:06",
+		"2020-01-01T00:00:07",
+		"--",
+		"2020-01-01T00:00:13",
+		"2020-01-01T00:00:14",
+		"2020-01-01T00:00:15",
+		"2020-01-01T00:00:16",
+		"2020-01-01T00:00:17",
+		"",
+	)
+	// filter on view with context skips context lines
+	tm.replay(":f/level/info", "\r")
+	expectLines(t, scr, "2020-01-01T00:00:16", "")
+	tm.replay(":fu", "\r", ":fc", "\r")
+	expectLines(t, scr, "2020-01-01T00:00:05", "2020-01-01T00:00:06", "2020-01-01T00:00:14")
+	tm.replay(":fr", "\r", ":fc", "\r")
+	expectStatus(t, scr, "context is available for filtered views only")
+}
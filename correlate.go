@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+// CorrelationTags returns configured names of tags identifying request (trace, correlation and so on)
+func CorrelationTags() []string {
+	return viper.GetStringSlice("correlation.tags")
+}
+
+// CorrelationTag returns the first configured correlation tag present in record m
+func CorrelationTag(m map[string]interface{}) (string, bool) {
+	for _, t := range CorrelationTags() {
+		if v, ok := lookupTag(m, t); ok && v != nil && tagToString(v) != "" {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+// Follow returns view with all the file's records having tag equal to value ordered by time;
+//
+//	if records have span and parent span tags they are ordered as span tree (see Depth)
+func (f *FileView) Follow(tag string, value interface{}) *FileView {
+	type record struct {
+		ln     int
+		time   valueKey
+		span   string
+		parent string
+	}
+	ret := &FileView{parent: f, file: f.file, name: fmt.Sprintf("%s follow %s", tag, tagToString(value)), index: []int{}, unordered: true}
+	ret.step = viewStep{Follow: &followStep{Tag: tag, Value: tagToString(value)}}
+	spanTag := viper.GetString("correlation.span")
+	parentTag := viper.GetString("correlation.parent")
+	val := tagToString(value)
+	recs := []record{}
+	tree := false
+	for i := 0; i < f.file.LinesCount(); i++ {
+		m := f.file.Line(i)
+		if v, ok := lookupTag(m, tag); !ok || tagToString(v) != val {
+			continue
+		}
+		r := record{ln: i, time: newValueKey(f.file.TagValue(m, TagTime)), span: fmt.Sprintf("#%d", i)}
+		if s, ok := lookupTag(m, spanTag); ok && spanTag != "" {
+			r.span = tagToString(s)
+		}
+		if p, ok := lookupTag(m, parentTag); ok && parentTag != "" && p != nil && tagToString(p) != "" {
+			r.parent = tagToString(p)
+			tree = true
+		}
+		recs = append(recs, r)
+	}
+	sort.SliceStable(recs, func(i, j int) bool { return recs[i].time.compare(recs[j].time) < 0 })
+	if !tree {
+		for _, r := range recs {
+			ret.index = append(ret.index, r.ln)
+		}
+		return ret
+	}
+
+	// span tree: records of the span, then its children spans; spans are ordered by their first record
+	spans := map[string][]int{}
+	parents := map[string]string{}
+	order := []string{}
+	for _, r := range recs {
+		if _, ok := spans[r.span]; !ok {
+			order = append(order, r.span)
+		}
+		spans[r.span] = append(spans[r.span], r.ln)
+		if r.parent != "" {
+			parents[r.span] = r.parent
+		}
+	}
+	children := map[string][]string{}
+	roots := []string{}
+	for _, s := range order {
+		p, ok := parents[s]
+		if _, known := spans[p]; ok && known && p != s {
+			children[p] = append(children[p], s)
+		} else {
+			roots = append(roots, s)
+		}
+	}
+	visited := map[string]bool{}
+	var visit func(span string, depth int)
+	visit = func(span string, depth int) {
+		if visited[span] {
+			return
+		}
+		visited[span] = true
+		for _, ln := range spans[span] {
+			ret.index = append(ret.index, ln)
+			ret.depth = append(ret.depth, depth)
+		}
+		for _, c := range children[span] {
+			visit(c, depth+1)
+		}
+	}
+	for _, s := range roots {
+		visit(s, 0)
+	}
+	// spans in parent cycles are not reachable from roots
+	for _, s := range order {
+		visit(s, 0)
+	}
+	return ret
+}
+
+// Depth returns depth in span tree of view line idx (0 if view is not a tree)
+func (f *FileView) Depth(idx int) int {
+	if idx < 0 || idx >= len(f.depth) {
+		return 0
+	}
+	return f.depth[idx]
+}
+
+// follow pushes view with records sharing current record's correlation tag (or given tag)
+func (t *term) follow(tag string) {
+	m := t.f.Line(t.current)
+	if m == nil {
+		return
+	}
+	if tag == "" {
+		var ok bool
+		if tag, ok = CorrelationTag(m); !ok {
+			t.message = "no correlation tag in record"
+			return
+		}
+	}
+	v, ok := lookupTag(m, tag)
+	if !ok {
+		t.message = fmt.Sprintf("%s: no such tag in record", tag)
+		return
+	}
+	ln := t.f.FileLine(t.f.Position() + t.current)
+	defer t.saveUndo(t.f.Steps())
+	t.f = t.f.Follow(tag, v)
+	idx, _ := t.f.ViewLine(ln)
+	t.goToLine(idx + 1)
+}
+
+func followCommandExecute(t *term) {
+	tag := ""
+	if len(t.command) > len(":follow ") {
+		tag = t.command[len(":follow "):]
+	}
+	t.follow(tag)
+}
+
+func followCommandOptions(t *term) {
+	t.command = ":follow "
+	t.options = newOptionsFromArray(CorrelationTags(), false)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFollow(t *testing.T) {
+	resetConfig("correlation.tags", []string{"ctx.trace_id"})
+	tm, scr := newTestTerm(t, 80, 8, []string{
+		`{"time": "2020-01-01T00:00:03", "level": "info", "msg": "child", "ctx": {"trace_id": "t1"}, "span_id": "s2", "parent_span_id": "s1"}`,
+		`{"time": "2020-01-01T00:00:01", "level": "info", "msg": "other", "ctx": {"trace_id": "t2"}}`,
+		`{"time": "2020-01-01T00:00:02", "level": "info", "msg": "root", "ctx": {"trace_id": "t1"}, "span_id": "s1"}`,
+		`{"time": "2020-01-01T00:00:04", "level": "info", "msg": "root again", "ctx": {"trace_id": "t1"}, "span_id": "s1"}`,
+	})
+	tm.replay("c")
+	expectStatus(t, scr, "ctx.trace_id follow t1")
+	expectLines(t, scr,
+		"2020-01-01T00:00:02  info root",
+		"2020-01-01T00:00:04  info root again",
+		"2020-01-01T00:00:03  info   child",
+		"",
+	)
+	if l := scr.Line(tm.current + 1); !strings.Contains(l, "child") {
+		t.Errorf("current record should be kept, current line is %q", l)
+	}
+	tm.replay(":fu", "\r", keyDown, ":follow ctx.trace_id", "\r")
+	expectLines(t, scr, "2020-01-01T00:00:01  info other", "")
+	tm.replay(":follow span_id", "\r")
+	expectStatus(t, scr, "span_id: no such tag in record")
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// Decoder - decodes record's bytes into tags
+type Decoder interface {
+	// Match reports whether b looks like a record of the format (otherwise the line is raw)
+	Match(b []byte) bool
+	// Decode returns record's tags
+	Decode(b []byte) (map[string]interface{}, error)
+}
+
+const (
+	FormatAuto   = "auto"
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+	FormatDocker = "docker"
+	FormatCRI    = "cri"
+)
+
+// decoders - known input formats
+var decoders = map[string]Decoder{
+	FormatJSON:   jsonDecoder{},
+	FormatLogfmt: logfmtDecoder{},
+	FormatDocker: dockerDecoder{},
+	FormatCRI:    criDecoder{},
+}
+
+// detectOrder - order of formats checking while detection: wrappers go before formats they wrap
+var detectOrder = []string{FormatDocker, FormatCRI, FormatJSON, FormatLogfmt}
+
+// detectLines - how many not empty lines are checked while format detection
+const detectLines = 20
+
+// detectFormat returns the format matching most of the first lines of the file (json if none)
+func detectFormat(f *os.File) string {
+	f.Seek(0, 0)
+	defer f.Seek(0, 0)
+	s := bufio.NewScanner(io.LimitReader(f, detectSize))
+	s.Buffer(make([]byte, bufSize), detectSize)
+	counts := map[string]int{}
+	for n := 0; n < detectLines && s.Scan(); {
+		b := bytes.TrimSpace(s.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		n++
+		matched := false
+		for _, name := range detectOrder {
+			if decoders[name].Match(b) {
+				counts[name]++
+				matched = true
+				break
+			}
+		}
+		if !matched && (b[0] == '{' || b[0] == '[') {
+			// json even if it is not valid: may be multiline record
+			counts[FormatJSON]++
+		}
+	}
+	format := FormatJSON
+	for _, name := range detectOrder {
+		if counts[name] > counts[format] {
+			format = name
+		}
+	}
+	return format
+}
+
+type jsonDecoder struct{}
+
+func (jsonDecoder) Match(b []byte) bool {
+	return isJSONLine(b)
+}
+
+func (jsonDecoder) Decode(b []byte) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	err := decodeJSON(b, &m)
+	floatNumbers(m)
+	return m, err
+}
+
+// decodeJSON decodes b keeping numbers as json.Number (see floatNumbers)
+func decodeJSON(b []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(v); err != nil {
+		return err
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return errors.New("invalid data after json value")
+	}
+	return nil
+}
+
+// maxExactFloat - integers greater than it can not be kept in float64 exactly
+const maxExactFloat = 1 << 53
+
+// floatNumbers replaces json.Number values of v by float64 ones except for integers which would lose precision
+// (epoch nanoseconds, ids); returns v
+func floatNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, e := range val {
+			val[k] = floatNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range val {
+			val[i] = floatNumbers(e)
+		}
+	case json.Number:
+		if i, err := val.Int64(); err == nil && (i > maxExactFloat || i < -maxExactFloat) {
+			return val
+		}
+		if f, err := val.Float64(); err == nil {
+			return f
+		}
+	}
+	return v
+}
+
+// logfmtDecoder - decoder of key=value records (values are kept as strings, keys without value are true)
+type logfmtDecoder struct{}
+
+func (logfmtDecoder) Match(b []byte) bool {
+	m, err := decodeLogfmt(string(b))
+	if err != nil {
+		return false
+	}
+	for _, v := range m {
+		if _, ok := v.(string); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (logfmtDecoder) Decode(b []byte) (map[string]interface{}, error) {
+	return decodeLogfmt(string(b))
+}
+
+func decodeLogfmt(s string) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	i := 0
+	for {
+		for i < len(s) && isLogfmtSpace(s[i]) {
+			i++
+		}
+		if i == len(s) {
+			return m, nil
+		}
+		from := i
+		for i < len(s) && s[i] != '=' && !isLogfmtSpace(s[i]) {
+			if s[i] == '"' {
+				return m, errors.New("logfmt: quote in key")
+			}
+			i++
+		}
+		key := s[from:i]
+		if i == len(s) || s[i] != '=' {
+			m[key] = true
+			continue
+		}
+		i++
+		if i < len(s) && s[i] == '"' {
+			val := strings.Builder{}
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+					switch s[i] {
+					case 'n':
+						val.WriteByte('\n')
+					case 't':
+						val.WriteByte('\t')
+					case 'r':
+						val.WriteByte('\r')
+					default:
+						val.WriteByte(s[i])
+					}
+					continue
+				}
+				val.WriteByte(s[i])
+			}
+			if i == len(s) {
+				return m, errors.New("logfmt: unterminated quoted value")
+			}
+			i++
+			m[key] = val.String()
+			continue
+		}
+		from = i
+		for i < len(s) && !isLogfmtSpace(s[i]) {
+			i++
+		}
+		m[key] = s[from:i]
+	}
+}
+
+func isLogfmtSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// decodeText decodes text of wrapped record: json, logfmt or plain text as message
+func decodeText(text string) map[string]interface{} {
+	b := []byte(text)
+	for _, d := range []Decoder{jsonDecoder{}, logfmtDecoder{}} {
+		if d.Match(b) {
+			if m, err := d.Decode(b); err == nil {
+				return m
+			}
+		}
+	}
+	return map[string]interface{}{"msg": text}
+}
+
+// putOuterTag adds wrapper's tag to the record; if record has the same tag the wrapper's one is prefixed with format name
+func putOuterTag(m map[string]interface{}, format, tag string, val interface{}) {
+	if _, ok := m[tag]; ok {
+		tag = format + "." + tag
+	}
+	m[tag] = val
+}
+
+// dockerDecoder - decoder of docker json-file log: {"log":"<line>\n","stream":"stdout","time":"..."}
+type dockerDecoder struct{}
+
+type dockerRecord struct {
+	Log    *string `json:"log"`
+	Stream string  `json:"stream"`
+	Time   string  `json:"time"`
+}
+
+func (dockerDecoder) Match(b []byte) bool {
+	if !isJSONLine(b) {
+		return false
+	}
+	r := dockerRecord{}
+	return json.Unmarshal(b, &r) == nil && r.Log != nil && (r.Stream != "" || r.Time != "")
+}
+
+func (dockerDecoder) Decode(b []byte) (map[string]interface{}, error) {
+	r := dockerRecord{}
+	if err := json.Unmarshal(b, &r); err != nil || r.Log == nil {
+		if err == nil {
+			err = errors.New("docker: no log field")
+		}
+		return map[string]interface{}{}, err
+	}
+	m := decodeText(strings.TrimRight(*r.Log, "\r\n"))
+	putOuterTag(m, FormatDocker, "stream", r.Stream)
+	putOuterTag(m, FormatDocker, "time", r.Time)
+	return m, nil
+}
+
+// criDecoder - decoder of kubernetes CRI log: <time> <stream> <F|P> <line>
+//
+//	partial (P) lines are not joined, they have tag partial
+type criDecoder struct{}
+
+func splitCRI(b []byte) (parts []string, ok bool) {
+	parts = strings.SplitN(string(b), " ", 4)
+	if len(parts) < 3 ||
+		(parts[1] != "stdout" && parts[1] != "stderr") ||
+		(parts[2] != "F" && parts[2] != "P") {
+		return nil, false
+	}
+	if _, ok := parseTime(parts[0]); !ok {
+		return nil, false
+	}
+	if len(parts) == 3 {
+		parts = append(parts, "")
+	}
+	return parts, true
+}
+
+func (criDecoder) Match(b []byte) bool {
+	_, ok := splitCRI(b)
+	return ok
+}
+
+func (criDecoder) Decode(b []byte) (map[string]interface{}, error) {
+	parts, ok := splitCRI(bytes.TrimRight(b, "\r"))
+	if !ok {
+		return map[string]interface{}{}, errors.New("cri: invalid line")
+	}
+	m := decodeText(parts[3])
+	putOuterTag(m, FormatCRI, "stream", parts[1])
+	putOuterTag(m, FormatCRI, "time", parts[0])
+	if parts[2] == "P" {
+		putOuterTag(m, FormatCRI, "partial", true)
+	}
+	return m, nil
+}
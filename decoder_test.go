@@ -0,0 +1,96 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeLogfmt(t *testing.T) {
+	for _, c := range []struct {
+		s        string
+		expected map[string]interface{}
+		err      bool
+	}{
+		{`ts=2020-01-01T00:00:00Z level=info msg=started`, map[string]interface{}{"ts": "2020-01-01T00:00:00Z", "level": "info", "msg": "started"}, false},
+		{`msg="hello world" n=1`, map[string]interface{}{"msg": "hello world", "n": "1"}, false},
+		{`msg="say \"hi\"\n\tnext" path="c:\\tmp"`, map[string]interface{}{"msg": "say \"hi\"\n\tnext", "path": `c:\tmp`}, false},
+		{`debug level=warn  empty= `, map[string]interface{}{"debug": true, "level": "warn", "empty": ""}, false},
+		{`msg="unterminated`, nil, true},
+		{`"key"=1`, nil, true},
+	} {
+		m, err := decodeLogfmt(c.s)
+		if c.err {
+			if err == nil {
+				t.Errorf("%s: error expected", c.s)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(m, c.expected) {
+			t.Errorf("%s: expected %v, got %v (%v)", c.s, c.expected, m, err)
+		}
+	}
+	if (logfmtDecoder{}).Match([]byte("just some words")) {
+		t.Error("text without values should not be logfmt")
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	for _, c := range []struct {
+		lines    []string
+		expected string
+	}{
+		{[]string{`{"msg": "a"}`, `{"msg": "b"}`}, FormatJSON},
+		{[]string{`level=info msg=a`, `level=warn msg=b`, `plain line`}, FormatLogfmt},
+		{[]string{`{`, `  "msg": "pretty"`, `}`}, FormatJSON},
+		{[]string{`plain`, `text`}, FormatJSON},
+	} {
+		if f := detectFormat(tempFile(t, c.lines)); f != c.expected {
+			t.Errorf("%v: expected %s, got %s", c.lines, c.expected, f)
+		}
+	}
+}
+
+func TestWrappedFormats(t *testing.T) {
+	for _, c := range []struct {
+		d        Decoder
+		s        string
+		expected map[string]interface{}
+	}{
+		{dockerDecoder{}, `{"log":"{\"level\":\"info\",\"msg\":\"a\"}\n","stream":"stdout","time":"2020-01-01T00:00:00Z"}`,
+			map[string]interface{}{"level": "info", "msg": "a", "stream": "stdout", "time": "2020-01-01T00:00:00Z"}},
+		{dockerDecoder{}, `{"log":"{\"time\":\"t1\",\"stream\":\"s\"}\n","stream":"stderr","time":"t2"}`,
+			map[string]interface{}{"time": "t1", "stream": "s", "docker.stream": "stderr", "docker.time": "t2"}},
+		{dockerDecoder{}, `{"log":"level=warn msg=b\n","stream":"stdout","time":"t"}`,
+			map[string]interface{}{"level": "warn", "msg": "b", "stream": "stdout", "time": "t"}},
+		{dockerDecoder{}, `{"log":"plain text\n","stream":"stdout","time":"t"}`,
+			map[string]interface{}{"msg": "plain text", "stream": "stdout", "time": "t"}},
+		{criDecoder{}, `2020-01-01T00:00:00.123456789Z stdout F {"msg":"a","time":"t1"}`,
+			map[string]interface{}{"msg": "a", "time": "t1", "stream": "stdout", "cri.time": "2020-01-01T00:00:00.123456789Z"}},
+		{criDecoder{}, `2020-01-01T00:00:00Z stderr P partial line`,
+			map[string]interface{}{"msg": "partial line", "stream": "stderr", "time": "2020-01-01T00:00:00Z", "partial": true}},
+		{criDecoder{}, `2020-01-01T00:00:00Z stdout F`,
+			map[string]interface{}{"msg": "", "stream": "stdout", "time": "2020-01-01T00:00:00Z"}},
+	} {
+		if !c.d.Match([]byte(c.s)) {
+			t.Errorf("%s: should match", c.s)
+			continue
+		}
+		m, err := c.d.Decode([]byte(c.s))
+		if err != nil || !reflect.DeepEqual(m, c.expected) {
+			t.Errorf("%s: expected %v, got %v (%v)", c.s, c.expected, m, err)
+		}
+	}
+	for _, s := range []string{`{"msg": "a"}`, `2020-01-01 stdout F x`, `2020-01-01T00:00:00Z stdin F x`} {
+		if (dockerDecoder{}).Match([]byte(s)) || (criDecoder{}).Match([]byte(s)) {
+			t.Errorf("%s: should not match", s)
+		}
+	}
+	lines := []string{`2020-01-01T00:00:00Z stdout F {"msg":"a"}`, `2020-01-01T00:00:01Z stdout F {"msg":"b"}`}
+	if f := detectFormat(tempFile(t, lines)); f != FormatCRI {
+		t.Errorf("expected cri format, got %s", f)
+	}
+	lines = []string{`{"log":"a\n","stream":"stdout","time":"t"}`}
+	if f := detectFormat(tempFile(t, lines)); f != FormatDocker {
+		t.Errorf("expected docker format, got %s", f)
+	}
+}
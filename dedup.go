@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// dedupStep - view with repeated records collapsed by Tags (message template if empty);
+//
+//	Global collapses all the records with the same key into the first one, not only consecutive ones
+type dedupStep struct {
+	Tags   []string `json:"tags,omitempty"`
+	Global bool     `json:"global,omitempty"`
+}
+
+// recordGroups - groups of records collapsed into one view line
+type recordGroups struct {
+	lines    [][]int
+	expanded map[int]bool
+	// row - group of the view line if it is the group's first line or -1
+	row []int
+}
+
+// templateParts - variable parts of messages replaced when message template is built
+var templateParts = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|0[xX][0-9a-fA-F]+|"[^"]*"|'[^']*'|[0-9]+(\.[0-9]+)?`)
+
+// messageTemplate returns message with numbers, ids and quoted strings replaced by *
+func messageTemplate(msg string) string {
+	return templateParts.ReplaceAllString(msg, "*")
+}
+
+// groupKey returns key records are compared by when collapsed
+func (f *FileView) groupKey(m map[string]interface{}, tags []string) string {
+	if len(tags) == 0 {
+		return messageTemplate(f.TagValue(m, TagMessage))
+	}
+	key := make([]string, len(tags))
+	for i, t := range tags {
+		if v, ok := lookupTag(m, t); ok {
+			key[i] = tagToString(v)
+		}
+	}
+	return strings.Join(key, "\x00")
+}
+
+// Collapse returns view where consecutive records equal on tags (or on message template if tags are empty)
+// are shown as one line; if global is set every distinct key is shown once (at its first occurrence)
+func (f *FileView) Collapse(tags []string, global bool) *FileView {
+	name := "collapse"
+	if global {
+		name = "dedup"
+	}
+	if len(tags) == 0 {
+		name += " template"
+	} else {
+		name += " " + strings.Join(tags, ",")
+	}
+	ret := &FileView{parent: f, file: f.file, name: name, unordered: f.unordered}
+	ret.step = viewStep{Dedup: &dedupStep{Tags: tags, Global: global}}
+	g := &recordGroups{expanded: map[int]bool{}}
+	byKey := map[string]int{}
+	last := ""
+	for i := 0; i < f.LinesCount(); i++ {
+		ln := f.FileLine(i)
+		if ln == lineSeparator || f.IsContext(i) {
+			continue
+		}
+		key := f.groupKey(f.file.Line(ln), tags)
+		if global {
+			if n, ok := byKey[key]; ok {
+				g.lines[n] = append(g.lines[n], ln)
+				continue
+			}
+			byKey[key] = len(g.lines)
+		} else if len(g.lines) > 0 && key == last {
+			g.lines[len(g.lines)-1] = append(g.lines[len(g.lines)-1], ln)
+			continue
+		}
+		last = key
+		g.lines = append(g.lines, []int{ln})
+	}
+	ret.groups = g
+	ret.regroup()
+	return ret
+}
+
+// regroup rebuilds index showing first lines of collapsed groups and all the lines of expanded ones
+func (f *FileView) regroup() {
+	g := f.groups
+	f.index = []int{}
+	g.row = []int{}
+	for i, lines := range g.lines {
+		if g.expanded[i] {
+			f.index = append(f.index, lines...)
+			g.row = append(g.row, i)
+			for range lines[1:] {
+				g.row = append(g.row, -1)
+			}
+			continue
+		}
+		f.index = append(f.index, lines[0])
+		g.row = append(g.row, i)
+	}
+	// lines of the expanded global group follow its first one
+	f.unordered = f.parent.unordered || f.step.Dedup.Global && len(g.expanded) > 0
+}
+
+// Group returns lines collapsed into view line idx (nil if view line does not start a group)
+func (f *FileView) Group(idx int) []int {
+	if f.groups == nil || idx < 0 || idx >= len(f.groups.row) || f.groups.row[idx] < 0 {
+		return nil
+	}
+	return f.groups.lines[f.groups.row[idx]]
+}
+
+// GroupHead returns view line starting the group view line idx belongs to
+func (f *FileView) GroupHead(idx int) int {
+	if f.groups == nil || idx >= len(f.groups.row) {
+		return idx
+	}
+	for idx > 0 && f.groups.row[idx] < 0 {
+		idx--
+	}
+	return idx
+}
+
+// GroupLine returns view line starting the group with file line ln
+func (f *FileView) GroupLine(ln int) (int, bool) {
+	if f.groups == nil {
+		return 0, false
+	}
+	for i, n := range f.groups.row {
+		if n < 0 {
+			continue
+		}
+		for _, l := range f.groups.lines[n] {
+			if l == ln {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// IsCollapsed reports whether view line idx starts a collapsed group of several records
+func (f *FileView) IsCollapsed(idx int) bool {
+	return len(f.Group(idx)) > 1 && !f.groups.expanded[f.groups.row[idx]]
+}
+
+// ToggleGroup expands (or collapses back) group with view line idx; returns false if there is nothing to toggle
+func (f *FileView) ToggleGroup(idx int) bool {
+	idx = f.GroupHead(idx)
+	lines := f.Group(idx)
+	if len(lines) < 2 {
+		return false
+	}
+	n := f.groups.row[idx]
+	if f.groups.expanded[n] {
+		delete(f.groups.expanded, n)
+	} else {
+		f.groups.expanded[n] = true
+	}
+	f.regroup()
+	return true
+}
+
+// toggleGroup expands or collapses group of the current line keeping it on the screen
+func (t *term) toggleGroup() {
+	idx := t.f.GroupHead(t.f.Position() + t.current)
+	if !t.f.ToggleGroup(idx) {
+		t.message = "not in a group"
+		return
+	}
+	t.goToLine(idx + 1)
+}
+
+// groupInfo returns count and time range of the group starting at view line idx (empty if there is no group)
+func (t *term) groupInfo(idx int) string {
+	f := t.f
+	lines := f.Group(idx)
+	if len(lines) < 2 {
+		return ""
+	}
+	from := t.formatTime(f.file.TagValue(f.file.Line(lines[0]), TagTime))
+	to := t.formatTime(f.file.TagValue(f.file.Line(lines[len(lines)-1]), TagTime))
+	if f.groups.expanded[f.groups.row[idx]] {
+		return fmt.Sprintf(" [×%d]", len(lines))
+	}
+	return fmt.Sprintf(" ×%d (%s .. %s)", len(lines), from, to)
+}
+
+// collapseCommandExecute processes :collapse [tag...] and :dedup [tag...]
+func collapseCommandExecute(t *term) {
+	args := strings.Fields(t.command)
+	global := args[0] == ":dedup"
+	ln := t.f.FileLine(t.f.Position() + t.current)
+	defer t.saveUndo(t.f.Steps())
+	t.f = t.f.Collapse(args[1:], global)
+	idx, ok := t.f.ViewLine(ln)
+	if !ok {
+		// the line is hidden in a group
+		if gi, found := t.f.GroupLine(ln); found {
+			idx = gi
+		} else if global {
+			idx = 0
+		}
+	}
+	t.goToLine(idx + 1)
+}
+
+func collapseCommandOptions(t *term) {
+	if !strings.HasSuffix(t.command, " ") {
+		t.command += " "
+	}
+	t.options = newOptionsFromArray(t.f.KnownTags(), false)
+}
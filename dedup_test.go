@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCollapse(t *testing.T) {
+	resetConfig()
+	lines := []string{}
+	for i, m := range []string{"start", "retry 1", "retry 2", "retry 3", "done", "retry 4"} {
+		lines = append(lines, `{"time": "2020-01-01T00:00:0`+string(rune('0'+i))+`", "level": "info", "msg": "`+m+`"}`)
+	}
+	tm, scr := newTestTerm(t, 80, 8, lines)
+	tm.replay(keyDown, keyDown, ":collapse", "\r")
+	expectLines(t, scr,
+		"2020-01-01T00:00:00  info start",
+		"2020-01-01T00:00:01  info retry 1 ×3 (2020-01-01T00:00:01 .. 2020-01-01T00:00:03",
+		"2020-01-01T00:00:04  info done",
+		"2020-01-01T00:00:05  info retry 4",
+	)
+	if tm.current != 1 {
+		t.Errorf(":collapse should go to the group of the current record, current line is %d", tm.current)
+	}
+	// enter expands the group, then opens the record
+	tm.replay("\r")
+	expectLines(t, scr, "", "2020-01-01T00:00:01  info retry 1 [×3]", "2020-01-01T00:00:02  info retry 2")
+	tm.replay(keyDown, "\r")
+	if tm.mode != modeRecord || !strings.Contains(scr.String(), "retry 2") {
+		t.Errorf("enter on expanded group should show the record:\n%s", scr)
+	}
+	tm.replay("q")
+	// z collapses the group from any of its lines
+	tm.replay("z")
+	expectLines(t, scr, "", "2020-01-01T00:00:01  info retry 1 ×3", "2020-01-01T00:00:04  info done")
+	tm.replay(":fu", "\r", ":dedup", "\r")
+	expectLines(t, scr, "2020-01-01T00:00:00  info start", "2020-01-01T00:00:01  info retry 1 ×4", "2020-01-01T00:00:04  info done")
+}
+
+func TestCollapseKeepsOrder(t *testing.T) {
+	resetConfig()
+	f := newTestFile(t, records(8))
+	v := f.View().Sort("n", true).Collapse([]string{"level"}, true)
+	v.ToggleGroup(0)
+	v.ToggleGroup(0)
+	if !v.unordered {
+		t.Error("collapsed sorted view should stay unordered")
+	}
+	v = f.View().Collapse([]string{"level"}, true)
+	v.ToggleGroup(0)
+	v.ToggleGroup(0)
+	if v.unordered {
+		t.Error("view should be ordered when all the groups are collapsed")
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	keyDelete = "\033\133\063\176"
+	keyHome1  = "\033\133\061\176"
+	keyEnd4   = "\033\133\064\176"
+	keyHomeO  = "\033\117\110"
+	keyEndO   = "\033\117\106"
+
+	keyCtrlA = 1
+	keyCtrlE = 5
+	keyCtrlK = 11
+	keyCtrlU = 21
+	keyCtrlW = 23
+)
+
+// splitKeys splits input into keys: escape sequences, control characters and runes;
+//
+//	incomplete escape sequence or rune at the end is returned as rest
+func splitKeys(b []byte) (keys [][]byte, rest []byte) {
+	for len(b) > 0 {
+		n := keyLen(b)
+		if n == 0 {
+			return keys, b
+		}
+		keys = append(keys, b[:n])
+		b = b[n:]
+	}
+	return keys, nil
+}
+
+// keyLen returns length of the first key in b or 0 if it is incomplete
+func keyLen(b []byte) int {
+	if b[0] != keyEsc {
+		if b[0] < utf8.RuneSelf {
+			return 1
+		}
+		if !utf8.FullRune(b) {
+			return 0
+		}
+		_, n := utf8.DecodeRune(b)
+		return n
+	}
+	if len(b) == 1 {
+		// lone Esc key
+		return 1
+	}
+	switch b[1] {
+	case '[':
+		// CSI: parameters and the final byte
+		for i := 2; i < len(b); i++ {
+			if b[i] >= 0x40 && b[i] <= 0x7e {
+				return i + 1
+			}
+		}
+		return 0
+	case 'O':
+		if len(b) < 3 {
+			return 0
+		}
+		return 3
+	}
+	return 1
+}
+
+// cursorPos returns position (in bytes) of the cursor in the command line;
+//
+//	if command line was set outside of editor the cursor is at the end
+func (t *term) cursorPos() int {
+	if t.cursorCmd != t.command || t.cursor > len(t.command) {
+		t.cursor = len(t.command)
+		t.cursorCmd = t.command
+	}
+	return t.cursor
+}
+
+func (t *term) setCommand(cmd string, cursor int) {
+	t.command = cmd
+	t.cursor = cursor
+	t.cursorCmd = cmd
+}
+
+// editCommand processes editing keys in command line; returns false if key is not for editor
+func (t *term) editCommand(key []byte) bool {
+	pos := t.cursorPos()
+	cmd := t.command
+	switch string(key) {
+	case keyLeft:
+		if pos > 0 {
+			_, n := utf8.DecodeLastRuneInString(cmd[:pos])
+			t.setCommand(cmd, pos-n)
+		}
+		return true
+	case keyRight:
+		if pos < len(cmd) {
+			_, n := utf8.DecodeRuneInString(cmd[pos:])
+			t.setCommand(cmd, pos+n)
+		}
+		return true
+	case keyHome, keyHome1, keyHomeO, string(rune(keyCtrlA)):
+		t.setCommand(cmd, 0)
+		return true
+	case keyEnd, keyEnd4, keyEndO, string(rune(keyCtrlE)):
+		t.setCommand(cmd, len(cmd))
+		return true
+	case keyDelete:
+		if pos < len(cmd) {
+			_, n := utf8.DecodeRuneInString(cmd[pos:])
+			t.setCommand(cmd[:pos]+cmd[pos+n:], pos)
+		}
+		return true
+	case string(rune(keyBackspace)):
+		if pos > 0 {
+			_, n := utf8.DecodeLastRuneInString(cmd[:pos])
+			t.setCommand(cmd[:pos-n]+cmd[pos:], pos-n)
+		}
+		t.history.reset()
+		return true
+	case string(rune(keyCtrlU)):
+		// the first character (:, / or ?) is kept
+		if pos > 1 {
+			t.setCommand(cmd[:1]+cmd[pos:], 1)
+		}
+		return true
+	case string(rune(keyCtrlK)):
+		t.setCommand(cmd[:pos], pos)
+		return true
+	case string(rune(keyCtrlW)):
+		from := pos
+		for from > 1 && cmd[from-1] == ' ' {
+			from--
+		}
+		for from > 1 && !isWordDelimiter(cmd[from-1]) {
+			from--
+		}
+		if from == pos && from > 1 {
+			from--
+		}
+		t.setCommand(cmd[:from]+cmd[pos:], from)
+		return true
+	case string(rune(keyEsc)):
+		t.setCommand("", 0)
+		t.history.reset()
+		return true
+	}
+	r, _ := utf8.DecodeRune(key)
+	if r == utf8.RuneError || !unicode.IsPrint(r) {
+		return false
+	}
+	t.setCommand(cmd[:pos]+string(key)+cmd[pos:], pos+len(key))
+	t.history.reset()
+	return true
+}
+
+func isWordDelimiter(c byte) bool {
+	return c == ' ' || c == '/' || c == ':'
+}
+
+// placeCursor moves terminal cursor to its position in command line
+func (t *term) placeCursor() {
+	if t.command == "" || t.options != nil || t.histSearch != nil {
+		return
+	}
+	_, col := t.commandWindow()
+	t.goTo(t.h, col)
+}
+
+// commandWindow returns the part of the command line shown on the screen and cursor column in it:
+//
+//	the line is scrolled when the cursor is beyond the screen width (e.g. after paste of long value)
+func (t *term) commandWindow() (string, int) {
+	cmd := t.command
+	pos := t.cursorPos()
+	from := 0
+	if width(cmd[:pos]) >= t.w {
+		from = pos
+		for w := 0; from > 0; {
+			r, n := utf8.DecodeLastRuneInString(cmd[:from])
+			if w += runeWidth(r); w > t.w-1 {
+				break
+			}
+			from -= n
+		}
+	}
+	return cmd[from:], width(cmd[from:pos]) + 1
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitKeys(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		keys []string
+		rest string
+	}{
+		{"ab", []string{"a", "b"}, ""},
+		{"шя", []string{"ш", "я"}, ""},
+		{"a\xd1", []string{"a"}, "\xd1"},
+		{"\033[A\033OHx", []string{keyUp, keyHomeO, "x"}, ""},
+		{"\033[3~\033", []string{keyDelete, "\033"}, ""},
+		{"x\033[1", []string{"x"}, "\033[1"},
+		{"\033O", nil, "\033O"},
+	} {
+		keys, rest := splitKeys([]byte(c.in))
+		ks := []string(nil)
+		for _, k := range keys {
+			ks = append(ks, string(k))
+		}
+		if !reflect.DeepEqual(ks, c.keys) || string(rest) != c.rest {
+			t.Errorf("%q: expected %q and %q, got %q and %q", c.in, c.keys, c.rest, ks, rest)
+		}
+	}
+}
+
+func TestEditCommand(t *testing.T) {
+	resetConfig()
+	tm, scr := newTestTerm(t, 80, 5, records(5))
+	tm.replay(":", "f", "/", "m", "s", "g", "/", "ш", "я")
+	expectStatus(t, scr, ":f/msg/шя")
+	tm.replay(keyLeft, "\x7f")
+	expectStatus(t, scr, ":f/msg/я")
+	tm.replay("\x01", keyRight, keyDelete, "s")
+	expectStatus(t, scr, ":s/msg/я")
+	tm.replay("\x05", "\x17")
+	expectStatus(t, scr, ":s/msg/")
+	tm.replay(keyLeft, "\x0b")
+	expectStatus(t, scr, ":s/msg")
+	tm.replay("\x15")
+	if tm.command != ":" {
+		t.Errorf("Ctrl-U should keep the first character, got %q", tm.command)
+	}
+	tm.replay("\x1b")
+	if tm.command != "" {
+		t.Errorf("Esc should cancel the command, got %q", tm.command)
+	}
+}
+
+func TestLongCommand(t *testing.T) {
+	resetConfig()
+	tm, scr := newTestTerm(t, 20, 5, records(5))
+	long := ":f/msg/" + strings.Repeat("ab", 20) + "end"
+	tm.replay(long)
+	if l := scr.Line(5); !strings.HasSuffix(strings.TrimRight(l, " "), "abend") || strings.Contains(l, ":f/") {
+		t.Errorf("the end of long command should be shown: %q", l)
+	}
+	if scr.col != 19 {
+		t.Errorf("cursor should be in the last column, got %d", scr.col+1)
+	}
+	tm.replay(keyHome)
+	if l := scr.Line(5); !strings.HasPrefix(l, ":f/msg/abab") || scr.col != 0 {
+		t.Errorf("the beginning of command should be shown with cursor at it: %q, column %d", l, scr.col+1)
+	}
+	tm.replay("\x1b", ":f/msg/"+strings.Repeat("日", 20))
+	if cmd, col := tm.commandWindow(); cmd != strings.Repeat("日", 9) || col != 19 {
+		t.Errorf("wide characters should take two cells: %q, column %d", cmd, col)
+	}
+}
+
+func TestBackspaceRunes(t *testing.T) {
+	resetConfig()
+	tm, scr := newTestTerm(t, 80, 5, records(5))
+	tm.history = &history{}
+	tm.history.add(":f/msg/шя")
+	tm.replay("\x12", "ш", "я", "\x7f")
+	expectStatus(t, scr, "(reverse-search)'ш': :f/msg/шя")
+	tm.replay("\x1b", ":settag level ", "\t", "ш", "я", "\x7f")
+	if tm.options == nil || tm.options.prefix != "ш" {
+		t.Errorf("backspace should remove the last rune of options prefix: %+v", tm.options)
+	}
+}
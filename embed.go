@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// decodeEmbedded replaces string values containing json objects or arrays with decoded values:
+//
+//	values of the file's embedded tags (paths) and (if auto mode is on) of any tag; nested objects are visited too
+func (f *File) decodeEmbedded(m map[string]interface{}) {
+	if !f.embedAuto && len(f.embedTags) == 0 {
+		return
+	}
+	f.decodeEmbeddedIn("", m)
+}
+
+func (f *File) decodeEmbeddedIn(prefix string, m map[string]interface{}) {
+	for k, v := range m {
+		p := prefix + k
+		// scalars ("42", "true") are kept as strings
+		if s, ok := v.(string); ok && (f.embedTags[p] || f.embedAuto) && looksLikeJSON(s) {
+			var d interface{}
+			if err := decodeJSON([]byte(s), &d); err == nil {
+				d = floatNumbers(d)
+				m[k], v = d, d
+			}
+		}
+		if nm, ok := v.(map[string]interface{}); ok {
+			f.decodeEmbeddedIn(p+".", nm)
+		}
+	}
+}
+
+func looksLikeJSON(s string) bool {
+	s = strings.TrimSpace(s)
+	return len(s) > 1 && (s[0] == '{' && s[len(s)-1] == '}' || s[0] == '[' && s[len(s)-1] == ']')
+}
+
+// SetEmbedded sets decoding of json embedded in string values: for the given tags and for any value looking like json if auto
+func (f *File) SetEmbedded(auto bool, tags ...string) {
+	f.embedAuto = auto
+	f.embedTags = map[string]bool{}
+	for _, t := range tags {
+		f.embedTags[t] = true
+	}
+}
+
+// EmbeddedTags returns tags with embedded json and whether auto detection is on
+func (f *File) EmbeddedTags() ([]string, bool) {
+	tags := []string{}
+	for t := range f.embedTags {
+		tags = append(tags, t)
+	}
+	return tags, f.embedAuto
+}
+
+// lookupTag returns tag's value; tag may be path (dot separated) in nested objects
+func lookupTag(m map[string]interface{}, tag string) (interface{}, bool) {
+	if v, ok := m[tag]; ok {
+		return v, true
+	}
+	var cur interface{} = m
+	for _, p := range strings.Split(tag, ".") {
+		mm, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = mm[p]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// nestedTags calls fn for path of every field of nested objects in m
+func nestedTags(prefix string, m map[string]interface{}, fn func(path string)) {
+	for k, v := range m {
+		nm, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		p := prefix + k
+		for nk := range nm {
+			fn(p + "." + nk)
+		}
+		nestedTags(p+".", nm, fn)
+	}
+}
+
+// embedCommandExecute processes :embed (toggles auto decoding) and :embed <tag> (toggles decoding of tag)
+func embedCommandExecute(t *term) {
+	file := t.root.file
+	tags, auto := file.EmbeddedTags()
+	arg := strings.TrimSpace(strings.TrimPrefix(t.command, ":embed"))
+	if arg == "" {
+		auto = !auto
+		t.message = fmt.Sprintf("auto decoding of embedded json: %v", auto)
+	} else {
+		found := false
+		for i, tag := range tags {
+			if tag == arg {
+				tags = append(tags[:i], tags[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			tags = append(tags, arg)
+		}
+		t.message = fmt.Sprintf("decoding of embedded json in %s: %v", arg, !found)
+	}
+	file.SetEmbedded(auto, tags...)
+	for i := 0; i < knownTagsDepth && i < file.LinesCount(); i++ {
+		file.fillKnownTags(i)
+	}
+	t.redraw()
+}
+
+func embedCommandOptions(t *term) {
+	t.command = ":embed "
+	t.options = newOptionsFromArray(t.f.KnownTags(), false)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecodeEmbedded(t *testing.T) {
+	resetConfig()
+	f := newTestFile(t, []string{
+		`{"msg": "m", "id": "42", "flag": "true", "payload": "{\"user\": {\"id\": 7, \"trace\": 1577836800123456789}}", "req": {"body": "[1, 2]", "n": "3"}}`,
+	}, FileOptions{EmbeddedTags: []string{"id", "flag", "payload", "req.body", "req.n"}})
+	m := f.Line(0)
+	if v, _ := lookupTag(m, "payload.user.trace"); v != json.Number("1577836800123456789") {
+		t.Errorf("big integers should be kept exact: %#v", v)
+	}
+	if m["id"] != "42" || m["flag"] != "true" {
+		t.Errorf("scalar strings should be kept: %#v %#v", m["id"], m["flag"])
+	}
+	if v, ok := lookupTag(m, "payload.user.id"); !ok || v != float64(7) {
+		t.Errorf("payload should be decoded: %v", m["payload"])
+	}
+	if v, ok := lookupTag(m, "req.body"); !ok {
+		t.Errorf("nested tag should be decoded: %v", m["req"])
+	} else if a, ok := v.([]interface{}); !ok || len(a) != 2 {
+		t.Errorf("nested tag should be decoded: %#v", v)
+	}
+	if v, _ := lookupTag(m, "req.n"); v != "3" {
+		t.Errorf("nested scalar should be kept: %#v", v)
+	}
+
+	f = newTestFile(t, []string{`{"msg": "m", "a": {"b": "{\"c\": 1}"}, "s": "{not json}"}`}, FileOptions{EmbeddedAuto: true})
+	m = f.Line(0)
+	if v, _ := lookupTag(m, "a.b.c"); v != float64(1) {
+		t.Errorf("auto mode should decode nested values: %v", m["a"])
+	}
+	if m["s"] != "{not json}" {
+		t.Errorf("invalid json should be kept: %v", m["s"])
+	}
+}
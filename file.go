@@ -1,13 +1,17 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
+
+	"github.com/spf13/viper"
 )
 
 const bufSize = 1024
@@ -20,12 +24,20 @@ type line struct {
 	start  int64
 	len    int
 	cached *item
+	// raw - line is not json
+	raw bool
+	// cont - number of raw lines following the line (continuation lines)
+	cont int
 }
 type item struct {
 	m map[string]interface{}
 	l *line
 	n *item
 	p *item
+	// raw - text of not json line
+	raw string
+	// cont - continuation lines attached to the record
+	cont []string
 }
 type cache struct {
 	head *item
@@ -41,6 +53,18 @@ type File struct {
 	err       error
 	knownTags []string
 	tagNames  []string
+	// tagAliases - names of well known tags to look for
+	tagAliases [TagOther][]string
+	rawCount   int
+	format     string
+	decoder    Decoder
+	embedAuto  bool
+	embedTags  map[string]bool
+	levels     levelTable
+	// attachRaw - raw lines following json record are shown as its continuation
+	attachRaw bool
+	// computed - virtual tags added to every decoded record
+	computed []computedTag
 }
 
 // FileView - view on File (filtered, sorted and so on)
@@ -51,6 +75,16 @@ type FileView struct {
 	name   string
 	pos    int
 	err    error
+	// matches - filter result when index is extended with context lines
+	matches []int
+	context int
+	// unordered - index is not in file order (depth is the span tree depth of the lines if any)
+	unordered bool
+	depth     []int
+	// groups - collapsed repeated records (see Collapse)
+	groups *recordGroups
+	// step - how view was made from parent (to be able to make it again)
+	step viewStep
 }
 
 type FilterOperator string
@@ -67,6 +101,8 @@ const (
 	FOGreaterOrEqual FilterOperator = "ge"
 	FOLessOrEqual    FilterOperator = "le"
 	FORegexp         FilterOperator = "regexp"
+	// FORaw - not json lines (and records with continuation lines); Tag and Mask are ignored
+	FORaw FilterOperator = "raw"
 )
 
 // SearchDirection type for search functions
@@ -130,51 +166,183 @@ const (
 )
 
 var wellKnownTagsNames = [TagOther][]string{
-	{"level", "lev", "l"},
-	{"time", "date", "datetime", "t"},
-	{"msg", "message", "m"},
+	{"level", "lev", "l", "severity", "loglevel", "log.level", "@l"},
+	{"time", "@timestamp", "timestamp", "ts", "date", "datetime", "t", "@t"},
+	{"msg", "message", "m", "@m", "@mt"},
+}
+
+// standardTagKeys - names of well known tags in config and commands
+var standardTagKeys = [TagOther]string{"level", "time", "message"}
+
+func standardTagByKey(key string) (Tag, bool) {
+	for t, k := range standardTagKeys {
+		if k == key {
+			return Tag(t), true
+		}
+	}
+	return TagOther, false
+}
+
+// tagAliasesFromConfig returns names of well known tags from config (tags.level, tags.time, tags.message) or defaults
+func tagAliasesFromConfig() [TagOther][]string {
+	aliases := wellKnownTagsNames
+	for t, k := range standardTagKeys {
+		if viper.IsSet("tags." + k) {
+			aliases[t] = viper.GetStringSlice("tags." + k)
+		}
+	}
+	return aliases
+}
+
+// FileOptions - options of file reading
+type FileOptions struct {
+	// Split - how file is split to records (SplitAuto by default)
+	Split SplitMode
+	// Format - records format: one of decoders' names or FormatAuto (default)
+	Format string
+	// EmbeddedTags - tags with json documents in string values to be decoded
+	EmbeddedTags []string
+	// EmbeddedAuto - decode any string value looking like json document
+	EmbeddedAuto bool
+	// TagAliases - names of well known tags in the order of preference; wellKnownTagsNames are used for empty ones
+	TagAliases [TagOther][]string
 }
 
-func NewFile(f *os.File) (*File, error) {
+func NewFile(f *os.File, opts ...FileOptions) (*File, error) {
 	fl := &File{
 		f:        f,
-		tagNames: []string{},
+		tagNames: make([]string, TagOther),
+	}
+	opt := FileOptions{}
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	fl.tagAliases = wellKnownTagsNames
+	for t, a := range opt.TagAliases {
+		if len(a) > 0 {
+			fl.tagAliases[t] = a
+		}
+	}
+	format := opt.Format
+	if format == "" || format == FormatAuto {
+		format = detectFormat(f)
 	}
+	d, ok := decoders[format]
+	if !ok {
+		return fl, fmt.Errorf("unknown format: %s", format)
+	}
+	fl.decoder = d
+	fl.format = format
+	fl.SetEmbedded(opt.EmbeddedAuto, opt.EmbeddedTags...)
+	split := opt.Split
+	switch split {
+	case "", SplitAuto, SplitLines, SplitValues:
+	default:
+		return fl, fmt.Errorf("unknown split mode: %s", split)
+	}
+	if format != FormatJSON {
+		split = SplitLines
+	} else if split == "" || split == SplitAuto {
+		split = detectSplit(f)
+	}
+	var err error
+	if split == SplitValues {
+		err = fl.indexValues()
+	} else {
+		err = fl.indexLines()
+	}
+	if err != nil {
+		return fl, err
+	}
+	for i := 0; i < knownTagsDepth && i < len(fl.index); i++ {
+		fl.fillKnownTags(i)
+	}
+	fl.sortKnownTags()
+	return fl, nil
+}
+
+// indexLines splits file to records by new line
+func (f *File) indexLines() error {
 	pos := int64(0)
 	length := 0
-	f.Seek(pos, 0)
+	f.f.Seek(pos, 0)
+	var cur []byte
+	record := -1
 	for {
-		l, err := f.Read(buffer)
+		l, err := f.f.Read(buffer)
 		// fmt.Printf("read %d: \n%s)\n", l, buffer[:l])
 		if l > 0 {
+			from := 0
 			for i := 0; i < l; i, length = i+1, length+1 {
 				if buffer[i] == '\n' {
-					fl.index = append(fl.index, line{start: pos, len: length})
+					cur = append(cur, buffer[from:i]...)
+					f.addLine(line{start: pos, len: length, raw: !f.decoder.Match(cur)}, &record)
 					// fmt.Printf("line %d(%d:%d)\n", len(fl.index), pos, length)
 					pos += int64(length + 1)
 					length = -1
+					cur = cur[:0]
+					from = i + 1
 				}
 			}
+			cur = append(cur, buffer[from:l]...)
 		}
 		if err == io.EOF {
-			break
+			return nil
 		}
 		if err != nil {
-			return fl, err
+			return err
 		}
-
 	}
-	for i := 0; i < knownTagsDepth && i < len(fl.index); i++ {
-		fl.fillKnownTags(i)
+}
+
+// addLine adds line to index; record is the index of the last json line (-1 if none yet)
+func (f *File) addLine(l line, record *int) {
+	if l.raw {
+		f.rawCount++
+		// lines before the first json record have no record to be attached to
+		if *record != -1 {
+			f.index[*record].cont++
+		}
+	} else {
+		*record = len(f.index)
 	}
-	fl.sortKnownTags()
-	return fl, nil
+	f.index = append(f.index, l)
+}
+
+func isJSONLine(b []byte) bool {
+	b = bytes.TrimSpace(b)
+	return len(b) > 0 && b[0] == '{' && json.Valid(b)
 }
 
 func (f *File) View() *FileView {
+	if f.attachRaw && f.rawCount > 0 {
+		v := &FileView{file: f, index: []int{}}
+		for i := 0; i < len(f.index); i++ {
+			v.index = append(v.index, i)
+			i += f.index[i].cont
+		}
+		return v
+	}
 	return &FileView{file: f}
 }
 
+// SetAttachRaw sets whether raw lines following json record are shown as its continuation
+//
+//	views created before are not changed
+func (f *File) SetAttachRaw(attach bool) {
+	f.attachRaw = attach
+}
+
+// AttachRaw reports whether raw lines are attached to the previous json records
+func (f *File) AttachRaw() bool {
+	return f.attachRaw
+}
+
+// RawCount returns count of not json lines
+func (f *File) RawCount() int {
+	return f.rawCount
+}
+
 func (f *File) LinesCount() int {
 	return len(f.index)
 }
@@ -218,7 +386,7 @@ func (f *FileView) Up() *FileView {
 	if f.parent == nil {
 		return f
 	}
-	f.parent.rewindTo(f.pos)
+	f.parent.rewindTo(f.FileLine(f.pos))
 	return f.parent
 }
 
@@ -231,7 +399,7 @@ func (f *FileView) Top() *FileView {
 	for p.parent != nil {
 		p = p.parent
 	}
-	p.rewindTo(f.pos)
+	p.rewindTo(f.FileLine(f.pos))
 	return p
 }
 
@@ -258,8 +426,11 @@ func (f *FileView) Levels() []string {
 }
 
 func (f *FileView) Filter(fltr Filter) *FileView {
-	ret := &FileView{parent: f, file: f.file, name: fltr.String(), index: []int{}}
+	ret := &FileView{parent: f, file: f.file, name: fltr.String(), index: []int{}, unordered: f.unordered, step: viewStep{Filter: &fltr}}
 	for i := 0; i < f.LinesCount(); i++ {
+		if f.IsContext(i) {
+			continue
+		}
 		it := f.item(i)
 		if it != nil && f.file.fit(it, fltr) {
 			ret.index = append(ret.index, f.getIndex(i))
@@ -280,10 +451,92 @@ func (f *FileView) Line(n int) map[string]interface{} {
 	return f.AbsLine(n + f.pos)
 }
 
+// RawLine returns text of line n (relative to position) if it is not json
+func (f *FileView) RawLine(n int) (string, bool) {
+	it := f.item(n + f.pos)
+	if it == nil || it.l == nil || !it.l.raw {
+		return "", false
+	}
+	return it.raw, true
+}
+
+// Continuation returns raw lines attached to the record n (relative to position)
+func (f *FileView) Continuation(n int) []string {
+	it := f.item(n + f.pos)
+	if it == nil {
+		return nil
+	}
+	return it.cont
+}
+
+// RawCount returns count of not json lines in file
+func (f *FileView) RawCount() int {
+	return f.file.RawCount()
+}
+
+// FileLine returns index of the file's line shown at view line idx or -1
+func (f *FileView) FileLine(idx int) int {
+	if idx < 0 || idx >= f.len() {
+		return -1
+	}
+	return f.getIndex(idx)
+}
+
+// ViewLine returns view line showing the file's line ln;
+//
+//	if ln is filtered out returns the nearest following line (or the last one) and false
+//	(the first line for unordered views)
+func (f *FileView) ViewLine(ln int) (int, bool) {
+	if f.unordered {
+		for i, l := range f.index {
+			if l == ln {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+	if f.index == nil {
+		if ln >= f.len() {
+			return f.len() - 1, false
+		}
+		return ln, ln >= 0
+	}
+	if f.matches != nil {
+		for i, l := range f.index {
+			if l >= ln {
+				return i, l == ln
+			}
+		}
+		return len(f.index) - 1, false
+	}
+	idx := sort.SearchInts(f.index, ln)
+	if idx == len(f.index) {
+		return idx - 1, false
+	}
+	return idx, f.index[idx] == ln
+}
+
 func (f *FileView) TagName(tag Tag) string {
 	return f.file.TagName(tag)
 }
 
+// TagValue returns value of well known tag of the record as string (empty if absent)
+func (f *FileView) TagValue(m map[string]interface{}, tag Tag) string {
+	v := f.file.TagValue(m, tag)
+	if v == nil {
+		return ""
+	}
+	return tagToString(v)
+}
+
+func (f *FileView) SetTagName(tag Tag, name string) {
+	f.file.SetTagName(tag, name)
+}
+
+func (f *FileView) IsStandardTag(tag string) bool {
+	return f.file.IsStandardTag(tag)
+}
+
 func (f *FileView) Level(m map[string]interface{}) int {
 	return f.file.Level(m)
 }
@@ -319,12 +572,14 @@ func (f *FileView) Search(mask string, from int, direction SearchDirection, rege
 	checkIdx()
 	start := from
 	for {
-		b := f.file.bytes(f.getIndex(from))
-		if b == nil {
-			return -1, "", errors.New("file read error")
-		}
-		if idx := strings.Index(string(b), mask); idx != -1 {
-			return from, mask, nil
+		if ln := f.getIndex(from); ln != lineSeparator {
+			b := f.file.bytes(ln)
+			if b == nil {
+				return -1, "", errors.New("file read error")
+			}
+			if idx := strings.Index(string(b), mask); idx != -1 {
+				return from, mask, nil
+			}
 		}
 		if direction == SearchForward {
 			from++
@@ -353,12 +608,14 @@ func (f *FileView) SearchTag(tag string, mask string, from int, direction Search
 	checkIdx()
 	start := from
 	for {
-		it := f.file.item(f.getIndex(from))
-		if it == nil {
-			return -1, errors.New("file read error")
-		}
-		if t, ok := it.m[tag]; ok && strings.Index(tagToString(t), mask) != -1 {
-			return from, nil
+		if ln := f.getIndex(from); ln != lineSeparator {
+			it := f.file.item(ln)
+			if it == nil {
+				return -1, errors.New("file read error")
+			}
+			if t, ok := lookupTag(it.m, tag); ok && strings.Index(tagToString(t), mask) != -1 {
+				return from, nil
+			}
 		}
 		if direction == SearchForward {
 			from++
@@ -380,29 +637,65 @@ func (f *File) Line(n int) map[string]interface{} {
 	return it.m
 }
 
+// TagName returns name of well known tag in file or empty string if it was not found
 func (f *File) TagName(tag Tag) string {
-	if int(tag) >= len(f.tagNames) {
-		fmt.Printf("tag is undefined: %d", tag)
-		return "--error"
+	if tag < 0 || int(tag) >= len(f.tagNames) {
+		return ""
 	}
 	return f.tagNames[tag]
 }
 
-func (f *File) Level(m map[string]interface{}) int {
-	lev := f.LevelName(m)
-	for l, ln := range levels {
-		if ln == lev {
-			return l
+// SetTagName overrides name of well known tag
+func (f *File) SetTagName(tag Tag, name string) {
+	if tag < 0 || int(tag) >= len(f.tagNames) {
+		return
+	}
+	f.tagNames[tag] = name
+	f.addKnownTag(name)
+}
+
+// TagValue returns value of well known tag of the record (nil if tag is undefined or absent)
+func (f *File) TagValue(m map[string]interface{}, tag Tag) interface{} {
+	name := f.TagName(tag)
+	if name == "" {
+		return nil
+	}
+	v, _ := lookupTag(m, name)
+	return v
+}
+
+// IsStandardTag reports whether tag is used as one of well known tags
+func (f *File) IsStandardTag(tag string) bool {
+	for _, n := range f.tagNames {
+		if n == tag {
+			return true
 		}
 	}
+	return false
+}
+
+func (f *File) Level(m map[string]interface{}) int {
+	if l := f.TagValue(m, TagLevel); l != nil {
+		return f.decodeLevel(tagToString(l))
+	}
 	return -1
 }
 
+// LevelName returns name of the record's level or its value if it is unknown
 func (f *File) LevelName(m map[string]interface{}) string {
-	if l, ok := m[f.TagName(TagLevel)].(string); ok {
-		return strings.ToLower(l)
+	l := f.TagValue(m, TagLevel)
+	if l == nil {
+		return ""
 	}
-	return ""
+	if lev := f.decodeLevel(tagToString(l)); lev != -1 {
+		return levels[lev]
+	}
+	return strings.ToLower(tagToString(l))
+}
+
+// SetLevels sets levels vocabulary
+func (f *File) SetLevels(lt levelTable) {
+	f.levels = lt
 }
 
 func (f *File) Err() error {
@@ -410,6 +703,9 @@ func (f *File) Err() error {
 }
 
 func (f Filter) String() string {
+	if f.Operator == FORaw {
+		return string(FORaw)
+	}
 	return fmt.Sprintf("%s %s %s", f.Tag, f.Operator, f.Mask)
 }
 func (f *FileView) item(idx int) *item {
@@ -432,7 +728,21 @@ func (f *File) item(n int) *item {
 	}
 	buf := f.bytes(n)
 	it := f.cache.item(&l)
-	f.err = json.Unmarshal(buf, &it.m)
+	it.raw, it.cont = "", nil
+	if l.raw {
+		if buf != nil {
+			it.raw = string(buf)
+		}
+		return it
+	}
+	it.m, f.err = f.decoder.Decode(buf)
+	f.decodeEmbedded(it.m)
+	f.computeTags(it.m)
+	if f.attachRaw {
+		for i := 1; i <= l.cont; i++ {
+			it.cont = append(it.cont, string(f.bytes(n+i)))
+		}
+	}
 	return it
 }
 
@@ -453,16 +763,19 @@ func (f *File) bytes(n int) []byte {
 }
 
 func (f *File) fit(it *item, q Filter) bool {
+	if q.Operator == FORaw {
+		return it.l != nil && it.l.raw || len(it.cont) > 0
+	}
 	//TODO correctly process not strings (especially numbers)
 	if q.Tag != "" {
-		if t, ok := it.m[q.Tag]; ok {
+		if t, ok := lookupTag(it.m, q.Tag); ok {
 			val := tagToString(t)
 			islevel := q.Tag == f.TagName(TagLevel)
 			lev := -1
 			reqLev := -1
 			if islevel {
 				lev = f.decodeLevel(val)
-				reqLev = f.decodeLevel(q.Mask)
+				reqLev = f.decodeLevelMask(q.Mask)
 			}
 			switch q.Operator {
 			case FOEqual:
@@ -498,13 +811,21 @@ func (f *File) fit(it *item, q Filter) bool {
 }
 
 func (f *File) decodeLevel(lev string) int {
-	lev = strings.ToLower(lev)
-	for l, ln := range levels {
-		if ln == lev {
+	if f.levels == nil {
+		return levelPresets["default"].decode(lev)
+	}
+	return f.levels.decode(lev)
+}
+
+// decodeLevelMask returns level of filter's mask: level names (as completion offers them) or values of the file
+func (f *File) decodeLevelMask(mask string) int {
+	m := strings.ToLower(strings.TrimSpace(mask))
+	for l, name := range levels {
+		if name == m {
 			return l
 		}
 	}
-	return -1
+	return f.decodeLevel(mask)
 }
 
 //
@@ -523,6 +844,7 @@ func (f *File) fillKnownTags(n int) {
 	for tag := range it.m {
 		f.addKnownTag(tag)
 	}
+	nestedTags("", it.m, f.addKnownTag)
 }
 
 func (f *File) addKnownTag(tag string) {
@@ -541,7 +863,7 @@ func (f *File) addKnownTag(tag string) {
 func (f *File) sortKnownTags() {
 	pos := 0
 	for t := TagLevel; t < TagOther; t++ {
-		if f.putStandardTag(wellKnownTagsNames[t], t) {
+		if f.putStandardTag(f.tagAliases[t], t, pos) {
 			pos++
 		}
 	}
@@ -561,22 +883,13 @@ func (f *File) sortKnownTags() {
 	//}
 }
 
-func (f *File) putStandardTag(from []string, idx Tag) bool {
+// putStandardTag looks for the first of from in known tags, moves it to pos and uses as well known tag idx
+func (f *File) putStandardTag(from []string, idx Tag, pos int) bool {
 	for _, s := range from {
 		for i, tag := range f.knownTags {
 			if s == tag {
-				if i != int(idx) {
-					f.knownTags[idx], f.knownTags[i] = f.knownTags[i], f.knownTags[idx]
-				}
-				if len(f.tagNames) <= int(idx) {
-					names := f.tagNames
-					f.tagNames = make([]string, idx+1)
-					for ni, name := range names {
-						f.tagNames[ni] = name
-					}
-				}
+				f.knownTags[pos], f.knownTags[i] = f.knownTags[i], f.knownTags[pos]
 				f.tagNames[idx] = s
-
 				return true
 			}
 		}
@@ -598,17 +911,14 @@ func (f *FileView) len() int {
 	return len(f.file.index)
 }
 
+// rewindTo sets position to the view line showing the file's line idx (or the nearest one)
 func (f *FileView) rewindTo(idx int) {
-	if f.index != nil {
-		for i, pos := range f.index {
-			if pos >= idx {
-				f.pos = i
-				break
-			}
-		}
+	if idx < 0 {
 		return
 	}
-	f.pos = idx
+	if pos, _ := f.ViewLine(idx); pos >= 0 {
+		f.pos = pos
+	}
 }
 
 func (c cache) item(forLine *line) *item {
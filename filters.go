@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// maxUndo - how many filter stack changes may be undone
+const maxUndo = 100
+
+// saveUndo saves filter stack before its change if it differs from the current one
+func (t *term) saveUndo(before []viewStep) {
+	if reflect.DeepEqual(before, t.f.Steps()) {
+		return
+	}
+	t.undo = append(t.undo, before)
+	if len(t.undo) > maxUndo {
+		t.undo = t.undo[1:]
+	}
+	t.redo = nil
+}
+
+func (t *term) undoFilters() {
+	if len(t.undo) == 0 {
+		t.message = "nothing to undo"
+		return
+	}
+	steps := t.undo[len(t.undo)-1]
+	t.undo = t.undo[:len(t.undo)-1]
+	t.redo = append(t.redo, t.f.Steps())
+	t.rebuild(steps)
+}
+
+func (t *term) redoFilters() {
+	if len(t.redo) == 0 {
+		t.message = "nothing to redo"
+		return
+	}
+	steps := t.redo[len(t.redo)-1]
+	t.redo = t.redo[:len(t.redo)-1]
+	t.undo = append(t.undo, t.f.Steps())
+	t.rebuild(steps)
+}
+
+// rebuild makes views of steps from the root one keeping current record if possible
+func (t *term) rebuild(steps []viewStep) {
+	ln := t.f.FileLine(t.f.Position() + t.current)
+	v := t.root
+	for _, s := range steps {
+		nv, err := s.apply(v)
+		if err != nil {
+			t.message = err.Error()
+		}
+		v = nv
+	}
+	t.f = v
+	if t.filtersPanel != nil {
+		t.filtersPanel.clamp(len(steps))
+	}
+	idx, _ := t.f.ViewLine(ln)
+	if t.mode == modeNormal {
+		t.goToLine(idx + 1)
+	} else {
+		t.f.SetPosition(idx)
+		t.current = 0
+		t.clampPosition()
+		t.redraw()
+	}
+}
+
+// changeStep applies fn to the step n (1-based) of the filter stack and rebuilds views
+func (t *term) changeStep(n int, fn func(steps []viewStep, i int) ([]viewStep, error)) {
+	steps := t.f.Steps()
+	if n < 1 || n > len(steps) {
+		t.message = fmt.Sprintf("no filter %d", n)
+		return
+	}
+	changed, err := fn(append([]viewStep{}, steps...), n-1)
+	if err != nil {
+		t.message = err.Error()
+		return
+	}
+	t.rebuild(changed)
+	t.saveUndo(steps)
+}
+
+func toggleStep(steps []viewStep, i int) ([]viewStep, error) {
+	steps[i].Disabled = !steps[i].Disabled
+	return steps, nil
+}
+
+func deleteStep(steps []viewStep, i int) ([]viewStep, error) {
+	return append(steps[:i], steps[i+1:]...), nil
+}
+
+func editStep(mask string) func(steps []viewStep, i int) ([]viewStep, error) {
+	return func(steps []viewStep, i int) ([]viewStep, error) {
+		s := steps[i]
+		switch {
+		case s.Filter != nil:
+			f := *s.Filter
+			f.Mask = mask
+			s.Filter = &f
+		case s.Follow != nil:
+			f := *s.Follow
+			f.Value = mask
+			s.Follow = &f
+		default:
+			return steps, fmt.Errorf("filter %d can not be edited", i+1)
+		}
+		steps[i] = s
+		return steps, nil
+	}
+}
+
+// stepMask returns editable value of the step
+func stepMask(s viewStep) string {
+	switch {
+	case s.Filter != nil:
+		return s.Filter.Mask
+	case s.Follow != nil:
+		return s.Follow.Value
+	}
+	return ""
+}
+
+// filtersPanel - state of filter stack browser
+type filtersPanel struct {
+	// selected - selected level: 0 is the root view
+	selected int
+}
+
+func (p *filtersPanel) clamp(levels int) {
+	if p.selected > levels {
+		p.selected = levels
+	}
+	if p.selected < 0 {
+		p.selected = 0
+	}
+}
+
+// filtersCommandExecute processes :filters (shows filter stack) and
+// :filters toggle|delete <n>, :filters edit <n> <mask>, :filters undo|redo
+func filtersCommandExecute(t *term) {
+	args := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(t.command, ":filters")), " ", 3)
+	switch args[0] {
+	case "":
+		t.filtersPanel = &filtersPanel{selected: len(t.f.Steps())}
+		t.mode = modeFilters
+		t.redraw()
+		return
+	case "undo":
+		t.undoFilters()
+		return
+	case "redo":
+		t.redoFilters()
+		return
+	}
+	if len(args) < 2 {
+		t.message = "usage: :filters [toggle|delete <n>|edit <n> <mask>|undo|redo]"
+		return
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil {
+		t.message = fmt.Sprintf("%s: invalid filter number", args[1])
+		return
+	}
+	switch args[0] {
+	case "toggle":
+		t.changeStep(n, toggleStep)
+	case "delete":
+		t.changeStep(n, deleteStep)
+	case "edit":
+		mask := ""
+		if len(args) == 3 {
+			mask = args[2]
+		}
+		t.changeStep(n, editStep(mask))
+	default:
+		t.message = fmt.Sprintf("%s: unknown filters command", args[0])
+	}
+}
+
+func filtersCommandOptions(t *term) {
+	t.command = ":filters "
+	t.options = newOptions("toggle", "toggle ", "delete", "delete ", "edit", "edit ", "undo", "undo", "redo", "redo")
+}
+
+// showFilters draws filter stack: root view and every filter with count of records
+func (t *term) showFilters() {
+	t.clear()
+	t.goTo(1, 1)
+	t.write(fmt.Sprintf(templBold, "filters: space - toggle, e - edit, d - delete, u - undo, ctrl-r - redo, enter - go to view, esc - close"))
+	views := []*FileView{}
+	for v := t.f; v != nil; v = v.parent {
+		views = append([]*FileView{v}, views...)
+	}
+	steps := t.f.Steps()
+	for i, v := range views {
+		row := i + 2
+		if row >= t.h {
+			break
+		}
+		name := "all records"
+		if i > 0 {
+			name = t.redactor.Step(steps[i-1]).String()
+		}
+		line := t.sanitizer.String(fmt.Sprintf("%3d %s (%d)", i, name, v.LinesCount()))
+		t.goTo(row, 1)
+		if i == t.filtersPanel.selected {
+			t.setColor(fgBlack, bgWhite)
+		}
+		if i > 0 && steps[i-1].Disabled {
+			t.write(dim + line + " [off]")
+		} else {
+			t.write(line)
+		}
+		t.resetColor()
+	}
+	t.message = fmt.Sprintf("%d filters", len(steps))
+}
+
+// processFiltersPanel processes keys of filter stack browser
+func (t *term) processFiltersPanel(cmd []byte, length int) {
+	p := t.filtersPanel
+	levels := len(t.f.Steps())
+	key := string(cmd[:length])
+	switch key {
+	case keyUp, "k":
+		p.selected--
+	case keyDown, "j":
+		p.selected++
+	case " ":
+		if p.selected > 0 {
+			t.changeStep(p.selected, toggleStep)
+		}
+	case "d":
+		if p.selected > 0 {
+			t.changeStep(p.selected, deleteStep)
+		}
+	case "u":
+		t.undoFilters()
+	case string(rune(keyCtrlR)):
+		t.redoFilters()
+	case "e":
+		if p.selected > 0 {
+			t.closeFilters()
+			n := p.selected
+			t.command = fmt.Sprintf(":filters edit %d %s", n, stepMask(t.f.Steps()[n-1]))
+			return
+		}
+	case string(rune(keyEnter)):
+		// go to the selected level
+		t.closeFilters()
+		for i := levels; i > p.selected; i-- {
+			t.f = t.f.Up()
+		}
+		t.clampPosition()
+		t.redraw()
+		return
+	case string(rune(keyEsc)), "q":
+		t.closeFilters()
+		t.redraw()
+		return
+	}
+	p.clamp(len(t.f.Steps()))
+	t.redraw()
+}
+
+func (t *term) closeFilters() {
+	t.mode = modeNormal
+	t.filtersPanel = nil
+	t.message = ""
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestDefaultFiltersAreSteps(t *testing.T) {
+	resetConfig("filters", []string{"level/error"})
+	tm, scr := newFilteredTestTerm(t, 80, 6, records(20))
+	expectLines(t, scr, "2020-01-01T00:00:02 error", "2020-01-01T00:00:06 error")
+	tm.replay(":filters toggle 1", "\r")
+	if n := tm.f.LinesCount(); n != 20 {
+		t.Errorf("disabled default filter should show all the records, got %d", n)
+	}
+	tm.replay(":undo", "\r")
+	if n, s := tm.f.LinesCount(), len(tm.f.Steps()); n != 5 || s != 1 {
+		t.Errorf("undo should restore the only default filter, got %d records and %d filters", n, s)
+	}
+	tm.replay(":f/n/1/$", "\r", ":filters delete 2", "\r", ":undo", "\r")
+	if n, s := tm.f.LinesCount(), len(tm.f.Steps()); n != 3 || s != 2 {
+		t.Errorf("expected 3 records and 2 filters, got %d records and %d filters", n, s)
+	}
+	tm.replay(":fr", "\r")
+	if n := tm.f.LinesCount(); n != 20 {
+		t.Errorf(":fr should show all the records, got %d", n)
+	}
+}
+
+func TestFiltersPanel(t *testing.T) {
+	resetConfig()
+	tm, scr := newTestTerm(t, 80, 8, records(20))
+	tm.replay(":f/level/error", "\r", ":f/n/1/$", "\r", ":filters", "\r")
+	expectLines(t, scr, "filters:", "  0 all records (20)", "  1 level eq error (5)", "  2 n regexp 1 (3)")
+	// toggle the first filter: the second one is applied to all records
+	tm.replay("k", " ")
+	expectLines(t, scr, "", "", "  1 level eq error (20)", "  2 n regexp 1 (11)")
+	tm.replay("d")
+	expectLines(t, scr, "", "", "  1 n regexp 1 (11)", "")
+	tm.replay("u")
+	expectLines(t, scr, "", "", "  1 level eq error (20)", "  2 n regexp 1 (11)")
+	tm.replay("\x12")
+	expectLines(t, scr, "", "", "  1 n regexp 1 (11)", "")
+	tm.replay("u", "u")
+	expectLines(t, scr, "", "", "  1 level eq error (5)", "  2 n regexp 1 (3)")
+	tm.replay("e")
+	if tm.command != ":filters edit 1 error" {
+		t.Errorf("e should start editing of the selected filter, got %q", tm.command)
+	}
+	tm.replay("\x17", "warn", "\r")
+	if n := tm.f.LinesCount(); n != 3 {
+		t.Errorf("edited filter should select warn records with 1, got %d", n)
+	}
+	tm.replay(":filters", "\r", "k", "\r")
+	if n, s := tm.f.LinesCount(), len(tm.f.Steps()); n != 5 || s != 1 {
+		t.Errorf("enter should go to the selected view, got %d records and %d filters", n, s)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/viper"
+)
+
+const keyCtrlR = 18
+
+// history - previously executed command lines
+type history struct {
+	items []string
+	// pos - index of the item shown while browsing (len(items) if not browsing)
+	pos int
+	// typed - command line typed before browsing; browsing shows items starting with it
+	typed string
+}
+
+// histSearch - state of reverse search (Ctrl-R)
+type histSearch struct {
+	query string
+	// pos - index of the found item or -1
+	pos int
+}
+
+// historyFile returns path of history file: config option history.file or jlv/history in user's state dir
+func historyFile() string {
+	if f := viper.GetString("history.file"); f != "" {
+		return f
+	}
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "jlv", "history")
+}
+
+// loadHistory reads history from file (missing file is not an error)
+func loadHistory(name string) (*history, error) {
+	h := &history{}
+	if name == "" {
+		return h, nil
+	}
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if l := s.Text(); l != "" {
+			h.items = append(h.items, l)
+		}
+	}
+	h.pos = len(h.items)
+	return h, s.Err()
+}
+
+// save writes last history.size items to file
+func (h *history) save(name string) error {
+	if name == "" {
+		return nil
+	}
+	items := h.items
+	if size := viper.GetInt("history.size"); size > 0 && len(items) > size {
+		items = items[len(items)-size:]
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, []byte(strings.Join(items, "\n")+"\n"), 0600)
+}
+
+// add appends command line; the same earlier item is removed
+func (h *history) add(cmd string) {
+	for i, it := range h.items {
+		if it == cmd {
+			h.items = append(h.items[:i], h.items[i+1:]...)
+			break
+		}
+	}
+	h.items = append(h.items, cmd)
+	h.pos = len(h.items)
+	h.typed = ""
+}
+
+// prev returns previous item starting with the typed command line
+func (h *history) prev(current string) (string, bool) {
+	if h.pos == len(h.items) {
+		h.typed = current
+	}
+	for i := h.pos - 1; i >= 0; i-- {
+		if strings.HasPrefix(h.items[i], h.typed) {
+			h.pos = i
+			return h.items[i], true
+		}
+	}
+	return "", false
+}
+
+// next returns next item starting with the typed command line or the typed command line itself
+func (h *history) next() (string, bool) {
+	if h.pos == len(h.items) {
+		return "", false
+	}
+	for i := h.pos + 1; i < len(h.items); i++ {
+		if strings.HasPrefix(h.items[i], h.typed) {
+			h.pos = i
+			return h.items[i], true
+		}
+	}
+	h.pos = len(h.items)
+	return h.typed, true
+}
+
+// reset stops browsing
+func (h *history) reset() {
+	h.pos = len(h.items)
+	h.typed = ""
+}
+
+// search returns index of the latest item before from containing query or -1
+func (h *history) search(query string, from int) int {
+	if from > len(h.items) {
+		from = len(h.items)
+	}
+	for i := from - 1; i >= 0; i-- {
+		if strings.Contains(h.items[i], query) {
+			return i
+		}
+	}
+	return -1
+}
+
+// processHistoryKey processes Up and Down in command line; returns false if key is not for history
+func (t *term) processHistoryKey(key string) bool {
+	var cmd string
+	var ok bool
+	switch key {
+	case keyUp:
+		cmd, ok = t.history.prev(t.command)
+	case keyDown:
+		cmd, ok = t.history.next()
+	default:
+		return false
+	}
+	if ok && cmd != "" {
+		t.command = cmd
+	}
+	return true
+}
+
+func (t *term) startHistorySearch() {
+	t.histSearch = &histSearch{pos: -1}
+}
+
+// processHistorySearch processes keys while reverse search: characters extend query,
+// Ctrl-R looks for older item, Enter executes found item, Esc cancels search
+func (t *term) processHistorySearch(cmd []byte, length int) {
+	s := t.histSearch
+	if length == 1 {
+		switch cmd[0] {
+		case keyCtrlR:
+			from := s.pos
+			if from == -1 {
+				// nothing is found yet: search from the latest item
+				from = len(t.history.items)
+			}
+			if p := t.history.search(s.query, from); p != -1 {
+				s.pos = p
+			}
+			return
+		case keyEsc:
+			t.histSearch = nil
+			return
+		case keyEnter:
+			t.histSearch = nil
+			if s.pos != -1 {
+				t.command = t.history.items[s.pos]
+				t.history.add(t.command)
+				t.execute()
+			}
+			return
+		case keyBackspace:
+			if s.query != "" {
+				_, n := utf8.DecodeLastRuneInString(s.query)
+				s.query = s.query[:len(s.query)-n]
+				s.pos = t.history.search(s.query, len(t.history.items))
+			}
+			return
+		}
+	}
+	if cmd[0] >= ' ' && cmd[0] != keyBackspace {
+		s.query += string(cmd[:length])
+		s.pos = t.history.search(s.query, len(t.history.items))
+		return
+	}
+	// any other key accepts found item for editing
+	t.histSearch = nil
+	if s.pos != -1 {
+		t.command = t.history.items[s.pos]
+	}
+}
+
+// historySearchPrompt returns status line of reverse search
+func (t *term) historySearchPrompt() string {
+	found := ""
+	if t.histSearch.pos != -1 {
+		found = t.history.items[t.histSearch.pos]
+	} else if t.histSearch.query != "" {
+		found = "not found"
+	}
+	return fmt.Sprintf("(reverse-search)'%s': %s", t.histSearch.query, found)
+}
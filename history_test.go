@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	resetConfig("history.size", 3)
+	h := &history{}
+	for _, c := range []string{":f/level/warn", ":follow", ":f/msg/x", ":follow"} {
+		h.add(c)
+	}
+	if !reflect.DeepEqual(h.items, []string{":f/level/warn", ":f/msg/x", ":follow"}) {
+		t.Errorf("repeated command should be moved to the end: %v", h.items)
+	}
+	if c, _ := h.prev(":f/"); c != ":f/msg/x" {
+		t.Errorf("prev should recall item starting with typed text, got %q", c)
+	}
+	if c, _ := h.prev(""); c != ":f/level/warn" {
+		t.Errorf("prev should keep typed text while browsing, got %q", c)
+	}
+	if _, ok := h.prev(""); ok {
+		t.Error("there should be no more items")
+	}
+	h.next()
+	if c, _ := h.next(); c != ":f/" {
+		t.Errorf("next after the last item should return typed text, got %q", c)
+	}
+
+	dir, err := ioutil.TempDir("", "jlv-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	name := filepath.Join(dir, "state", "history")
+	h.add(":marks")
+	if err := h.save(name); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadHistory(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.items, []string{":f/msg/x", ":follow", ":marks"}) || loaded.pos != 3 {
+		t.Errorf("history.size last items should be saved: %v", loaded.items)
+	}
+	if h, err := loadHistory(filepath.Join(dir, "missing")); err != nil || len(h.items) != 0 {
+		t.Errorf("missing file should give empty history: %v", err)
+	}
+}
+
+func TestHistorySearch(t *testing.T) {
+	resetConfig()
+	tm, scr := newTestTerm(t, 80, 5, records(20))
+	tm.history = &history{}
+	tm.replay(":f/level/warn", "\r", ":fu", "\r", ":f/level/error", "\r", ":fu", "\r")
+	tm.replay(":", keyUp)
+	expectStatus(t, scr, ":fu")
+	tm.replay("\x1b", "\x12", "\x12")
+	expectStatus(t, scr, "(reverse-search)'': :fu")
+	tm.replay("\x12")
+	expectStatus(t, scr, "(reverse-search)'': :f/level/error")
+	tm.replay("\x1b", "\x12", "level", "\x12")
+	expectStatus(t, scr, "(reverse-search)'level': :f/level/warn")
+	tm.replay("\r")
+	expectStatus(t, scr, "level eq warn")
+	tm.replay(":fu", "\r", "\x12", "nothing")
+	expectStatus(t, scr, "'nothing': not found")
+}
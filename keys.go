@@ -0,0 +1,226 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// actions - named actions keys may be bound to
+var actions = map[string]func(*term){
+	"up":          (*term).up,
+	"down":        (*term).down,
+	"pgup":        (*term).pgUp,
+	"pgdn":        (*term).pgDn,
+	"home":        (*term).home,
+	"end":         (*term).end,
+	"search-next": func(t *term) { t.search(false) },
+	"search-prev": func(t *term) { t.search(true) },
+	"follow":      func(t *term) { t.follow("") },
+	"record": func(t *term) {
+		// collapsed group is expanded first
+		if t.f.IsCollapsed(t.f.Position() + t.current) {
+			t.toggleGroup()
+			return
+		}
+		t.mode = modeRecord
+		t.redraw()
+	},
+	"group":     (*term).toggleGroup,
+	"mark":      func(t *term) { t.pendingMark = 'm' },
+	"jump-mark": func(t *term) { t.pendingMark = '\'' },
+	"redraw":    (*term).redraw,
+	"redact":    (*term).toggleRedaction,
+	"quit":      func(t *term) { t.exit = true },
+}
+
+// defaultKeys - built-in key bindings; config option keys adds or overrides them
+var defaultKeys = map[string]string{
+	"j":      "down",
+	"k":      "up",
+	"G":      "end",
+	"n":      "search-next",
+	"N":      "search-prev",
+	"c":      "follow",
+	"m":      "mark",
+	"'":      "jump-mark",
+	"enter":  "record",
+	"z":      "group",
+	"up":     "up",
+	"down":   "down",
+	"home":   "home",
+	"end":    "end",
+	"pgup":   "pgup",
+	"pgdn":   "pgdn",
+	"ctrl-l": "redraw",
+	"R":      "redact",
+}
+
+// keyNames - names of special keys which may be used in bindings
+var keyNames = map[string]string{
+	"up":    keyUp,
+	"down":  keyDown,
+	"right": keyRight,
+	"left":  keyLeft,
+	"pgup":  keyPgUp,
+	"pgdn":  keyPgDn,
+	"home":  keyHome,
+	"end":   keyEnd,
+	"enter": string(rune(keyEnter)),
+	"tab":   string(rune(keyTab)),
+	"esc":   string(rune(keyEsc)),
+	"space": " ",
+	"f1":    "\033OP",
+	"f2":    "\033OQ",
+	"f3":    "\033OR",
+	"f4":    "\033OS",
+	"f5":    "\033[15~",
+	"f6":    "\033[17~",
+	"f7":    "\033[18~",
+	"f8":    "\033[19~",
+	"f9":    "\033[20~",
+	"f10":   "\033[21~",
+	"f11":   "\033[23~",
+	"f12":   "\033[24~",
+}
+
+// parseKey returns key sequence for the key name, single character or escape sequence
+func parseKey(name string) (string, error) {
+	if seq, ok := keyNames[strings.ToLower(name)]; ok {
+		return seq, nil
+	}
+	if l := strings.ToLower(name); strings.HasPrefix(l, "ctrl-") && len(l) == 6 && l[5] >= 'a' && l[5] <= 'z' {
+		return string(rune(l[5] - 'a' + 1)), nil
+	}
+	if len([]rune(name)) == 1 || strings.HasPrefix(name, "\033") {
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown key: %s", name)
+}
+
+// parseBinding parses "<name> = <value>" string
+func parseBinding(s string) (name string, value string, err error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+		return "", "", fmt.Errorf("invalid binding (should be <name> = <value>): %s", s)
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), nil
+}
+
+// isCommandString reports whether action is a command line (not a named action)
+func isCommandString(action string) bool {
+	return strings.HasPrefix(action, ":") || strings.HasPrefix(action, "/") || strings.HasPrefix(action, "?")
+}
+
+// fillKeys sets default bindings and bindings from config option keys (list of "<key> = <action or command>");
+//
+//	invalid bindings are skipped, the returned error lists all of them
+func (t *term) fillKeys() error {
+	t.keys = map[string]string{}
+	bind := func(key, action string) error {
+		seq, err := parseKey(key)
+		if err != nil {
+			return err
+		}
+		if _, ok := actions[action]; !ok && !isCommandString(action) {
+			return fmt.Errorf("unknown action for key %s: %s", key, action)
+		}
+		t.keys[seq] = action
+		return nil
+	}
+	for k, a := range defaultKeys {
+		bind(k, a)
+	}
+	errs := []string{}
+	for _, b := range viper.GetStringSlice("keys") {
+		k, a, err := parseBinding(b)
+		if err == nil {
+			err = bind(k, a)
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	return joinErrors(errs)
+}
+
+// joinErrors returns error with all the messages or nil if there are none
+func joinErrors(errs []string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errs, "; "))
+}
+
+// fillAliases registers commands from config option aliases (list of "<name> = <command>"), e.g. "errs = :f/level/warn/+";
+//
+//	aliases may not replace built-in commands
+func (t *term) fillAliases() error {
+	errs := []string{}
+	for _, b := range viper.GetStringSlice("aliases") {
+		name, cmd, err := parseBinding(b)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if !isCommandString(cmd) {
+			errs = append(errs, fmt.Sprintf("alias %s: command should start with :, / or ?", name))
+			continue
+		}
+		if t.isBuiltinCommand(":" + name) {
+			errs = append(errs, fmt.Sprintf("alias %s: shadows built-in command", name))
+			continue
+		}
+		t.commands[":"+name] = &command{
+			name:   name,
+			alias:  true,
+			execFn: func(t *term) { t.do(cmd) },
+		}
+	}
+	return joinErrors(errs)
+}
+
+// isBuiltinCommand reports whether command line cmd (without arguments) is taken by a built-in command
+func (t *term) isBuiltinCommand(cmd string) bool {
+	if filterSubcommands.MatchString(cmd) {
+		return true
+	}
+	for cl, c := range t.commands {
+		if c.alias {
+			continue
+		}
+		if cl == cmd {
+			return true
+		}
+		if c.regex != "" {
+			if m, _ := regexp.MatchString(c.regex, cmd); m {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// maxDoDepth - limit of nested aliases
+const maxDoDepth = 8
+
+// do executes named action or command line
+func (t *term) do(action string) {
+	if !isCommandString(action) {
+		if fn, ok := actions[action]; ok {
+			fn(t)
+		}
+		return
+	}
+	if t.doDepth >= maxDoDepth {
+		t.message = fmt.Sprintf("%s: aliases are nested too deep", action)
+		return
+	}
+	t.doDepth++
+	defer func() { t.doDepth-- }()
+	t.command = action
+	t.execute()
+}
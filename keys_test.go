@@ -0,0 +1,28 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyBindings(t *testing.T) {
+	resetConfig("keys", []string{"J = pgdn", "bad", "X = nothing", "M = mark", "ctrl-e = :errs"},
+		"aliases", []string{"errs = :f/level/error", "f = :f/level/warn", "fu = :f/level/warn", "fc2 = :fr", "12 = :fr", "oops"})
+	tm, scr := newTestTerm(t, 80, 6, records(30))
+	for _, m := range []string{"invalid binding", "unknown action for key X", "alias f: shadows built-in command", "alias fu: shadows", "alias fc2: shadows", "alias 12: shadows", "oops"} {
+		if !strings.Contains(tm.message, m) {
+			t.Errorf("expected error %q, got %q", m, tm.message)
+		}
+	}
+	tm.replay("J")
+	expectStatus(t, scr, "5(30)")
+	tm.replay("Ma", keyHome, "'a")
+	expectStatus(t, scr, "5(30)")
+	tm.replay("\x05")
+	expectLines(t, scr, " 2020-01-01T00:00:02 error")
+	tm.replay(":fu", "\r", ":f/level/info", "\r")
+	expectStatus(t, scr, "level eq info")
+	if n := len(tm.f.Steps()); n != 1 {
+		t.Errorf(":fu should go to the root view, got %d filters", n)
+	}
+}
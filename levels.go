@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// levelTable - maps level values (lower cased, numbers as strings) to levels (LevelTrace ... LevelFault)
+type levelTable map[string]int
+
+// levelPresets - built-in level vocabularies
+var levelPresets = map[string]levelTable{
+	"default": {
+		LevelTraceName: LevelTrace,
+		LevelDebugName: LevelDebug,
+		LevelInfoName:  LevelInfo,
+		LevelWarnName:  LevelWarn,
+		LevelErrorName: LevelError,
+		LevelFatalName: LevelFatal,
+		LevelFaultName: LevelFault,
+		"trc":          LevelTrace,
+		"dbg":          LevelDebug,
+		"verbose":      LevelDebug,
+		"inf":          LevelInfo,
+		"information":  LevelInfo,
+		"notice":       LevelInfo,
+		"wrn":          LevelWarn,
+		"warning":      LevelWarn,
+		"err":          LevelError,
+		"crit":         LevelFatal,
+		"critical":     LevelFatal,
+		"panic":        LevelFault,
+		"alert":        LevelFault,
+		"emerg":        LevelFault,
+		"emergency":    LevelFault,
+	},
+	// single letter levels (glog, zerolog console and so on)
+	"letters": {
+		"t": LevelTrace,
+		"d": LevelDebug,
+		"i": LevelInfo,
+		"w": LevelWarn,
+		"e": LevelError,
+		"f": LevelFatal,
+		"p": LevelFault,
+	},
+	// pino and bunyan numeric levels
+	"pino": {
+		"10": LevelTrace,
+		"20": LevelDebug,
+		"30": LevelInfo,
+		"40": LevelWarn,
+		"50": LevelError,
+		"60": LevelFatal,
+	},
+	// syslog severities
+	"syslog": {
+		"0": LevelFault,
+		"1": LevelFault,
+		"2": LevelFatal,
+		"3": LevelError,
+		"4": LevelWarn,
+		"5": LevelInfo,
+		"6": LevelInfo,
+		"7": LevelDebug,
+	},
+}
+
+// newLevelTable merges presets and custom mapping (value -> level name)
+func newLevelTable(presets []string, custom map[string]string) (levelTable, error) {
+	lt := levelTable{}
+	for _, p := range presets {
+		preset, ok := levelPresets[p]
+		if !ok {
+			return lt, fmt.Errorf("unknown levels preset: %s", p)
+		}
+		for k, v := range preset {
+			lt[k] = v
+		}
+	}
+	for k, v := range custom {
+		l := levelPresets["default"].decode(v)
+		if l == -1 {
+			return lt, fmt.Errorf("unknown level %s for %s", v, k)
+		}
+		lt[strings.ToLower(k)] = l
+	}
+	return lt, nil
+}
+
+// levelsFromConfig returns level table configured with levels.presets and levels.map
+func levelsFromConfig() (levelTable, error) {
+	return newLevelTable(viper.GetStringSlice("levels.presets"), viper.GetStringMapString("levels.map"))
+}
+
+// decode returns level of value or -1
+func (lt levelTable) decode(v string) int {
+	if l, ok := lt[strings.ToLower(strings.TrimSpace(v))]; ok {
+		return l
+	}
+	return -1
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestLevelTable(t *testing.T) {
+	lt, err := newLevelTable([]string{"default", "syslog"}, map[string]string{"Verbose": "trace", "boom": "fault"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for v, l := range map[string]int{"WARNING": LevelWarn, " err ": LevelError, "6": LevelInfo, "3": LevelError, "verbose": LevelTrace, "boom": LevelFault, "30": -1, "x": -1} {
+		if d := lt.decode(v); d != l {
+			t.Errorf("%q: expected %d, got %d", v, l, d)
+		}
+	}
+	if _, err := newLevelTable([]string{"nope"}, nil); err == nil {
+		t.Error("unknown preset should be an error")
+	}
+	if _, err := newLevelTable(nil, map[string]string{"a": "loud"}); err == nil {
+		t.Error("unknown level name should be an error")
+	}
+}
+
+func TestLevelPresets(t *testing.T) {
+	resetConfig("levels.presets", []string{"syslog"})
+	f := newTestFile(t, []string{
+		`{"time": "2020-01-01T00:00:00", "level": 6, "msg": "info"}`,
+		`{"time": "2020-01-01T00:00:01", "level": 4, "msg": "warning"}`,
+		`{"time": "2020-01-01T00:00:02", "level": 3, "msg": "error"}`,
+		`{"time": "2020-01-01T00:00:03", "level": 7, "msg": "debug"}`,
+	})
+	lt, err := levelsFromConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.SetLevels(lt)
+	scr := newVirtualScreen(80, 6)
+	tm, err := newTerm(f.View(), scr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm.redraw()
+	expectLines(t, scr, "2020-01-01T00:00:00  info info", "2020-01-01T00:00:01  warn warning", "2020-01-01T00:00:02 error error")
+	// level names are used in filters whatever values the file has
+	tm.replay(":f/level/warn/+", "\r")
+	expectLines(t, scr, "2020-01-01T00:00:01  warn warning", "2020-01-01T00:00:02 error error", "")
+	tm.replay(":fu", "\r", ":f/level/4/-", "\r")
+	expectLines(t, scr, "2020-01-01T00:00:00  info info", "2020-01-01T00:00:01  warn warning", "2020-01-01T00:00:03 debug debug")
+}
@@ -16,30 +16,89 @@ func main() {
 	flag.Bool("f", false, "continuous reading")
 	flag.String("filter", "", "filter on (tag=value)")
 	flag.String("cfg", ".jlv", "configuration file name (without extension)")
+	flag.Bool("attach-raw", false, "show not json lines as continuation of the previous record")
+	flag.Bool("ansi", false, "interpret ANSI colors in values (for trusted sources only)")
+	flag.String("format", FormatAuto, "records format: json, logfmt, docker, cri or auto")
+	flag.String("split", string(SplitAuto), "how file is split to records: lines, values (pretty-printed or concatenated json) or auto")
+	flag.String("profile", "", "config profile name (profile is selected by file name if not set)")
+	flag.String("session", "", "restore saved session (file name may be omitted)")
 
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 	pflag.Parse()
 	viper.BindPFlags(pflag.CommandLine)
 
+	setDefaults()
 	viper.SetConfigName(viper.GetString("cfg"))
 	viper.AddConfigPath("./cfg/")
 	viper.AddConfigPath("./")
 	viper.AutomaticEnv()
 	viper.ReadInConfig()
 
-	if pflag.NArg() == 0 {
+	var sess *session
+	if name := viper.GetString("session"); name != "" {
+		var err error
+		if sess, err = loadSession(name); err != nil {
+			fmt.Printf("error loading session: %v\n", err)
+			return
+		}
+		if !pflag.Lookup("format").Changed && sess.Format != "" {
+			viper.Set("format", sess.Format)
+		}
+		if !pflag.Lookup("attach-raw").Changed {
+			viper.Set("attach-raw", sess.AttachRaw)
+		}
+	}
+	fileName := pflag.Arg(0)
+	if fileName == "" && sess != nil {
+		fileName = sess.Files[0]
+	}
+	if fileName == "" {
 		fmt.Println("no filename found")
 		return
 	}
-	file, err := os.Open(pflag.Arg(0))
+	profile := viper.GetString("profile")
+	if profile == "" {
+		profile = matchProfile(fileName)
+	}
+	if profile != "" {
+		if err := applyProfile(profile); err != nil {
+			fmt.Printf("error applying profile: %v\n", err)
+			return
+		}
+	}
+	if err := levelColorsFromConfig(); err != nil {
+		fmt.Printf("error in colors config: %v\n", err)
+	}
+	file, err := os.Open(fileName)
 	if err != nil {
 		fmt.Printf("error open file: %v\n", err)
 	}
-	f, err := NewFile(file)
+	f, err := NewFile(file, FileOptions{
+		Split:        SplitMode(viper.GetString("split")),
+		Format:       viper.GetString("format"),
+		EmbeddedTags: viper.GetStringSlice("embedded.tags"),
+		EmbeddedAuto: viper.GetBool("embedded.auto"),
+		TagAliases:   tagAliasesFromConfig(),
+	})
 	if err != nil {
 		fmt.Printf("error reading file: %v\n", err)
+		return
+	}
+	f.SetAttachRaw(viper.GetBool("attach-raw"))
+	lt, err := levelsFromConfig()
+	if err != nil {
+		fmt.Printf("error in levels config: %v\n", err)
+	}
+	f.SetLevels(lt)
+	if err := f.computedFromConfig(); err != nil {
+		fmt.Printf("error in let config: %v\n", err)
 	}
-	err = startTerm(f.View())
+	v, err := defaultFilters(f.View())
+	if err != nil {
+		fmt.Printf("error in filters config: %v\n", err)
+		return
+	}
+	err = startTerm(v, sess)
 	if err != nil {
 		for i := 0; i < f.LinesCount(); i++ {
 			fmt.Printf("%02d: %s\n", i, string(f.bytes(i)))
@@ -49,6 +108,14 @@ func main() {
 	// start(pflag.Arg(0))
 }
 
+func setDefaults() {
+	viper.SetDefault("correlation.tags", []string{"trace_id", "traceId", "request_id", "requestId", "correlation_id", "correlationId"})
+	viper.SetDefault("correlation.span", "span_id")
+	viper.SetDefault("correlation.parent", "parent_span_id")
+	viper.SetDefault("levels.presets", []string{"default", "pino"})
+	viper.SetDefault("history.size", 1000)
+}
+
 func start(name string) {
 	file, err := os.Open(name)
 	if err != nil {
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// processMark finishes m<letter> or '<letter> command
+func (t *term) processMark(cmd []byte, length int) {
+	pending := t.pendingMark
+	t.pendingMark = 0
+	if length != 1 || !isMarkName(cmd[0]) {
+		return
+	}
+	if pending == 'm' {
+		t.setMark(cmd[0])
+	} else {
+		t.jumpToMark(cmd[0])
+	}
+}
+
+func isMarkName(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// setMark marks current record; marks keep file's line so they survive filtering
+func (t *term) setMark(name byte) {
+	ln := t.f.FileLine(t.f.Position() + t.current)
+	if ln == -1 {
+		return
+	}
+	first := len(t.marks) == 0
+	t.marks[name] = ln
+	t.message = fmt.Sprintf("mark %c: line %d", name, ln+1)
+	if first {
+		// gutter appears
+		t.redraw()
+	} else {
+		t.drawLine(t.current)
+	}
+}
+
+func (t *term) jumpToMark(name byte) {
+	ln, ok := t.marks[name]
+	if !ok {
+		t.message = fmt.Sprintf("mark %c is not set", name)
+		return
+	}
+	idx, exact := t.f.ViewLine(ln)
+	if idx < 0 {
+		return
+	}
+	if !exact {
+		t.message = fmt.Sprintf("mark %c: line %d is not in view", name, ln+1)
+	}
+	t.goToLine(idx + 1)
+}
+
+// markOf returns name of the mark set on the file's line ln or 0
+func (t *term) markOf(ln int) byte {
+	for name, l := range t.marks {
+		if l == ln {
+			return name
+		}
+	}
+	return 0
+}
+
+// gutter returns mark indicator for the view line n or empty string if no marks are set
+func (t *term) gutter(n int) string {
+	if len(t.marks) == 0 {
+		return ""
+	}
+	name := t.markOf(t.f.FileLine(t.f.Position() + n))
+	if name == 0 {
+		return " "
+	}
+	return fmt.Sprintf(templBold, string(name))
+}
+
+func marksCommandExecute(t *term) {
+	if len(t.marks) == 0 {
+		t.message = "no marks"
+		return
+	}
+	names := make([]byte, 0, len(t.marks))
+	for name := range t.marks {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	t.options = &options{replace: true}
+	for _, name := range names {
+		t.options.add(fmt.Sprintf(templBoldSuff, string(name), fmt.Sprintf(":%d", t.marks[name]+1)), fmt.Sprintf(":'%c", name))
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarks(t *testing.T) {
+	resetConfig()
+	tm, scr := newTestTerm(t, 80, 6, records(30))
+	tm.replay(keyDown, keyDown, "ma")
+	expectStatus(t, scr, "mark a: line 3")
+	expectLines(t, scr, " 2020-01-01T00:00:00", " 2020-01-01T00:00:01", "a2020-01-01T00:00:02")
+	tm.replay("G", "'a")
+	if l := scr.Line(tm.current + 1); !strings.Contains(l, "message 2") {
+		t.Errorf("'a should jump to message 2, current line is %q", l)
+	}
+	// marks survive filtering
+	tm.replay(":f/level/error", "\r", "G", "'a")
+	if l := scr.Line(tm.current + 1); !strings.HasPrefix(l, "a") || !strings.Contains(l, "message 2") {
+		t.Errorf("'a should jump to marked line in filtered view, current line is %q", l)
+	}
+	tm.replay(":fu", "\r", keyDown, "mb", ":'b", "\r")
+	expectStatus(t, scr, "4(30)")
+	tm.replay("'z")
+	expectStatus(t, scr, "mark z is not set")
+	tm.replay(":marks", "\r")
+	expectStatus(t, scr, "a:3 b:4")
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// profiles are config sections profiles.<name>; every option of the profile overrides the global one
+// (except changed command line flags), match (list of globs) and regex select profile by file path:
+//
+//	profiles:
+//	  nginx:
+//	    match: ["*access*.log"]
+//	    tags:
+//	      time: [time_local]
+//	    filters: ["status/400/+"]
+
+// profileKeys - options of the profile which are not copied to global config
+var profileKeys = map[string]bool{"match": true, "regex": true}
+
+// matchProfile returns name of the first (in alphabetical order) profile matching the file path or empty string
+func matchProfile(path string) string {
+	names := []string{}
+	for name := range viper.GetStringMap("profiles") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	for _, name := range names {
+		p := viper.Sub("profiles." + name)
+		if p == nil {
+			continue
+		}
+		for _, g := range p.GetStringSlice("match") {
+			if ok, _ := filepath.Match(g, filepath.Base(path)); ok {
+				return name
+			}
+			if ok, _ := filepath.Match(g, abs); ok {
+				return name
+			}
+		}
+		if r := p.GetString("regex"); r != "" {
+			if ok, _ := regexp.MatchString(r, abs); ok {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// applyProfile copies profile's options to global config
+func applyProfile(name string) error {
+	p := viper.Sub("profiles." + name)
+	if p == nil {
+		return fmt.Errorf("profile %s is not found", name)
+	}
+	for _, k := range p.AllKeys() {
+		if profileKeys[k] {
+			continue
+		}
+		if f := pflag.Lookup(k); f != nil && f.Changed {
+			continue
+		}
+		viper.Set(k, p.Get(k))
+	}
+	return nil
+}
+
+// levelColorsFromConfig overrides level colors with colors.<level>: <color name>
+func levelColorsFromConfig() error {
+	for lev, name := range levels {
+		c := viper.GetString("colors." + name)
+		if c == "" {
+			continue
+		}
+		fg, ok := colorNames[strings.ToLower(c)]
+		if !ok {
+			return fmt.Errorf("unknown color %s for %s", c, name)
+		}
+		levelColors[lev] = fg
+	}
+	return nil
+}
+
+var colorNames = map[string]int{
+	"black":   fgBlack,
+	"red":     fgRed,
+	"green":   fgGreen,
+	"yellow":  fgYellow,
+	"blue":    fgBlue,
+	"magenta": fgMagenta,
+	"cyan":    fgCyan,
+	"white":   fgWhite,
+	"default": fgDefault,
+}
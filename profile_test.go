@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestDefaultFilters(t *testing.T) {
+	resetConfig("filters", []string{"facility/kern", ":f/level/info", "/fields.x/1"})
+	f := newTestFile(t, []string{
+		`{"msg": "a", "facility": "kern", "level": "info", "fields": {"x": 1}}`,
+		`{"msg": "b", "facility": "user", "level": "info", "fields": {"x": 1}}`,
+		`{"msg": "c", "facility": "kern", "level": "warn", "fields": {"x": 1}}`,
+		`{"msg": "d", "facility": "kern", "level": "info", "fields": {"x": 2}}`,
+	})
+	v, err := defaultFilters(f.View())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.LinesCount() != 1 || v.Line(0)["msg"] != "a" {
+		t.Errorf("expected only record a, got %d records", v.LinesCount())
+	}
+	if len(v.Steps()) != 3 || v.Steps()[0].Filter.Tag != "facility" || v.Steps()[2].Filter.Tag != "fields.x" {
+		t.Errorf("unexpected filters: %v", v.Steps())
+	}
+}
+
+func TestProfile(t *testing.T) {
+	resetConfig()
+	viper.Set("profiles", map[string]interface{}{
+		"nginx": map[string]interface{}{"match": []string{"*access*.log"}, "columns": []string{"status"}},
+		"app":   map[string]interface{}{"regex": "/app/"},
+	})
+	if p := matchProfile("/var/log/nginx/access.log"); p != "nginx" {
+		t.Errorf("expected nginx profile, got %q", p)
+	}
+	if p := matchProfile("/srv/app/x.log"); p != "app" {
+		t.Errorf("expected app profile, got %q", p)
+	}
+	if p := matchProfile("/tmp/x.log"); p != "" {
+		t.Errorf("expected no profile, got %q", p)
+	}
+	if err := applyProfile("nginx"); err != nil {
+		t.Fatal(err)
+	}
+	if c := viper.GetStringSlice("columns"); len(c) != 1 || c[0] != "status" {
+		t.Errorf("profile options should be applied: %v", c)
+	}
+}
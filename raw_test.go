@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRawLines(t *testing.T) {
+	resetConfig()
+	lines := []string{
+		`{"time": "2020-01-01T00:00:00", "level": "error", "msg": "failed"}`,
+		`panic: boom`,
+		`	main.go:10`,
+		`{"time": "2020-01-01T00:00:01", "level": "info", "msg": "ok"}`,
+	}
+	tm, scr := newTestTerm(t, 80, 6, lines)
+	expectLines(t, scr, "2020-01-01T00:00:00 error failed", "panic: boom", " main.go:10", "2020-01-01T00:00:01  info ok")
+	expectStatus(t, scr, "1(4) raw:2")
+	tm.replay(":fraw", "\r")
+	expectLines(t, scr, "panic: boom", " main.go:10")
+	if n := tm.f.LinesCount(); n != 2 {
+		t.Errorf(":fraw should show 2 raw lines, got %d", n)
+	}
+	tm.replay(":fr", "\r", ":raw", "\r")
+	expectStatus(t, scr, "raw lines are attached to records")
+	expectLines(t, scr, "2020-01-01T00:00:00 error failed [+2]", "2020-01-01T00:00:01  info ok")
+	tm.replay(":raw", "\r")
+	expectLines(t, scr, "2020-01-01T00:00:00 error failed", "panic: boom")
+}
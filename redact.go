@@ -0,0 +1,241 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// redaction modes
+const (
+	RedactFull    = "full"
+	RedactPartial = "partial"
+	RedactHash    = "hash"
+)
+
+// redactPatterns - built-in patterns which may be used in redaction rules by name
+var redactPatterns = map[string]string{
+	"email":  `[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`,
+	"card":   `\b(?:\d[ -]?){12,18}\d\b`,
+	"bearer": `(?i)bearer\s+[a-zA-Z0-9._~+/=-]+`,
+	"ip":     `\b(?:\d{1,3}\.){3}\d{1,3}\b|\b(?:[0-9a-fA-F]{1,4}:){7}[0-9a-fA-F]{1,4}\b`,
+}
+
+// redactRule - rule rewriting value of the tag (path) or parts of values matching re
+type redactRule struct {
+	tag  string
+	re   *regexp.Regexp
+	mode string
+}
+
+// redactor rewrites records before they are shown; everything showing or writing record values
+// (screen, record details, status line and any future exporters) should take them from Record
+// (or Text and TagValue for single values), never from the file directly
+type redactor struct {
+	rules []redactRule
+	on    bool
+	// key - key of hashes (config option redact-key or random one, so hashes differ between sessions)
+	key []byte
+}
+
+// parseRedactRule parses rule "<target> = <mode>" where target is tag:<path>, re:<regexp> or built-in pattern name
+func parseRedactRule(s string) (redactRule, error) {
+	eq := strings.LastIndex(s, "=")
+	if eq < 0 {
+		return redactRule{}, fmt.Errorf("invalid redaction rule (should be <target> = <mode>): %s", s)
+	}
+	target, mode := strings.TrimSpace(s[:eq]), strings.TrimSpace(s[eq+1:])
+	r := redactRule{mode: mode}
+	switch mode {
+	case RedactFull, RedactPartial, RedactHash:
+	default:
+		return r, fmt.Errorf("invalid redaction mode (should be full, partial or hash): %s", mode)
+	}
+	var pattern string
+	switch {
+	case strings.HasPrefix(target, "tag:"):
+		r.tag = strings.TrimPrefix(target, "tag:")
+		return r, nil
+	case strings.HasPrefix(target, "re:"):
+		pattern = strings.TrimPrefix(target, "re:")
+	default:
+		var ok bool
+		if pattern, ok = redactPatterns[target]; !ok {
+			return r, fmt.Errorf("unknown redaction pattern: %s", target)
+		}
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return r, err
+	}
+	r.re = re
+	return r, nil
+}
+
+// redactorFromConfig makes redactor with rules from config option redact (list of "<target> = <mode>")
+func redactorFromConfig() (*redactor, error) {
+	rd := &redactor{key: []byte(viper.GetString("redact-key"))}
+	if len(rd.key) == 0 {
+		rd.key = make([]byte, 32)
+		if _, err := rand.Read(rd.key); err != nil {
+			return rd, err
+		}
+	}
+	for _, s := range viper.GetStringSlice("redact") {
+		r, err := parseRedactRule(s)
+		if err != nil {
+			return rd, err
+		}
+		rd.rules = append(rd.rules, r)
+	}
+	rd.on = len(rd.rules) > 0
+	return rd, nil
+}
+
+// mask rewrites value according to mode
+func (rd *redactor) mask(s string, mode string) string {
+	switch mode {
+	case RedactHash:
+		// the same values give the same hashes, so they may still be correlated;
+		// keyed hash of short values (ids, card numbers) can't be brute forced without the key
+		h := hmac.New(sha256.New, rd.key)
+		h.Write([]byte(s))
+		return "#" + hex.EncodeToString(h.Sum(nil)[:6])
+	case RedactPartial:
+		r := []rune(s)
+		if len(r) <= 6 {
+			return strings.Repeat("*", len(r))
+		}
+		return string(r[:1]) + strings.Repeat("*", len(r)-5) + string(r[len(r)-4:])
+	}
+	return "***"
+}
+
+// active reports whether redaction changes anything
+func (rd *redactor) active() bool {
+	return rd != nil && rd.on && len(rd.rules) > 0
+}
+
+// Text returns s with parts matching pattern rules masked
+func (rd *redactor) Text(s string) string {
+	if !rd.active() {
+		return s
+	}
+	for _, r := range rd.rules {
+		if r.re != nil {
+			s = r.re.ReplaceAllStringFunc(s, func(m string) string { return rd.mask(m, r.mode) })
+		}
+	}
+	return s
+}
+
+// Record returns copy of record m with values rewritten by rules (m itself if redaction is off)
+func (rd *redactor) Record(m map[string]interface{}) map[string]interface{} {
+	if !rd.active() || m == nil {
+		return m
+	}
+	return rd.value(m, "").(map[string]interface{})
+}
+
+func (rd *redactor) value(v interface{}, path string) interface{} {
+	for _, r := range rd.rules {
+		if r.tag != "" && r.tag == path && v != nil {
+			return rd.mask(tagToString(v), r.mode)
+		}
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(val))
+		for k, e := range val {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			ret[k] = rd.value(e, p)
+		}
+		return ret
+	case []interface{}:
+		ret := make([]interface{}, len(val))
+		for i, e := range val {
+			ret[i] = rd.value(e, path)
+		}
+		return ret
+	case string:
+		return rd.Text(val)
+	case float64:
+		// numbers may be card numbers or ids as well
+		s := strconv.FormatFloat(val, 'f', -1, 64)
+		if m := rd.Text(s); m != s {
+			return m
+		}
+	case json.Number:
+		if m := rd.Text(val.String()); m != val.String() {
+			return m
+		}
+	}
+	return v
+}
+
+// TagValue returns string value v of the tag path rewritten by rules
+func (rd *redactor) TagValue(path string, v interface{}) string {
+	if !rd.active() {
+		return tagToString(v)
+	}
+	return tagToString(rd.value(v, path))
+}
+
+// Step returns step with values rewritten by rules (for showing)
+func (rd *redactor) Step(s viewStep) viewStep {
+	if !rd.active() {
+		return s
+	}
+	if s.Follow != nil {
+		f := *s.Follow
+		f.Value = rd.TagValue(f.Tag, f.Value)
+		s.Follow = &f
+	}
+	if s.Filter != nil && s.Filter.Operator != FORaw {
+		f := *s.Filter
+		f.Mask = rd.TagValue(f.Tag, f.Mask)
+		s.Filter = &f
+	}
+	return s
+}
+
+// viewName returns name of the current view with redacted values
+func (t *term) viewName() string {
+	v := t.f
+	for v.step.Disabled && v.parent != nil {
+		// disabled view has name of its parent
+		v = v.parent
+	}
+	if s := v.step; (s.Follow != nil || s.Filter != nil) && t.redactor.active() {
+		s = t.redactor.Step(s)
+		s.Context = 0
+		return s.String()
+	}
+	return t.f.Name()
+}
+
+// toggleRedaction shows or hides redacted values
+func (t *term) toggleRedaction() {
+	if t.redactor == nil || len(t.redactor.rules) == 0 {
+		t.message = "no redaction rules"
+		return
+	}
+	t.redactor.on = !t.redactor.on
+	t.redraw()
+	if t.redactor.on {
+		t.message = "redaction: on"
+	} else {
+		t.message = "redaction: off (raw values are shown)"
+	}
+}
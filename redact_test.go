@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactor(t *testing.T) {
+	resetConfig("redact", []string{"email = hash", "card = partial", "tag:user.name = full", "re:secret-\\w+ = full"}, "redact-key", "k")
+	rd, err := redactorFromConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := rd.Record(map[string]interface{}{
+		"msg":  "mail bob@example.com and secret-abc",
+		"card": 4111111111111111.0,
+		"user": map[string]interface{}{"name": "Bob", "id": 7.0},
+		"list": []interface{}{"alice@example.com"},
+	})
+	if s := m["msg"].(string); strings.Contains(s, "bob@") || strings.Contains(s, "secret-abc") || !strings.Contains(s, "mail #") {
+		t.Errorf("patterns should be masked: %q", s)
+	}
+	if m["card"] != "4***********1111" {
+		t.Errorf("numbers should be masked: %v", m["card"])
+	}
+	if u := m["user"].(map[string]interface{}); u["name"] != "***" || u["id"] != 7.0 {
+		t.Errorf("only user.name should be masked: %v", u)
+	}
+	if l := m["list"].([]interface{}); strings.Contains(l[0].(string), "alice") {
+		t.Errorf("array values should be masked: %v", l)
+	}
+	if rd.Text("bob@example.com") != rd.Text("bob@example.com") {
+		t.Error("the same values should have the same hashes")
+	}
+	other := &redactor{rules: rd.rules, on: true, key: []byte("other")}
+	if rd.Text("bob@example.com") == other.Text("bob@example.com") {
+		t.Error("hashes should depend on the key")
+	}
+	rd.on = false
+	if m := rd.Record(map[string]interface{}{"card": 4111111111111111.0}); m["card"] != 4111111111111111.0 {
+		t.Errorf("values should be kept when redaction is off: %v", m["card"])
+	}
+}
+
+func TestRedactedFollowName(t *testing.T) {
+	resetConfig("redact", []string{"tag:trace_id = full"})
+	tm, scr := newTestTerm(t, 80, 6, []string{
+		`{"time": "2020-01-01T00:00:00", "level": "info", "msg": "a", "trace_id": "t-secret"}`,
+		`{"time": "2020-01-01T00:00:01", "level": "info", "msg": "b", "trace_id": "t-secret"}`,
+	})
+	tm.replay("c")
+	expectStatus(t, scr, "trace_id follow ***")
+	if strings.Contains(scr.String(), "t-secret") {
+		t.Errorf("trace id should be masked:\n%s", scr)
+	}
+	tm.replay("R")
+	tm.showPosition()
+	expectStatus(t, scr, "trace_id follow t-secret")
+}
+
+func TestRedactedFilterName(t *testing.T) {
+	resetConfig("redact", []string{"tag:user = full"})
+	tm, scr := newTestTerm(t, 80, 6, []string{
+		`{"time": "2020-01-01T00:00:00", "level": "info", "msg": "a", "user": "bob"}`,
+		`{"time": "2020-01-01T00:00:01", "level": "info", "msg": "b", "user": "alice"}`,
+	})
+	tm.replay(":f/user/bob", "\r")
+	expectStatus(t, scr, "user eq ***")
+	if s := tm.redactor.Step(tm.f.Steps()[0]).String(); s != "user eq ***" {
+		t.Errorf("filter mask should be redacted: %q", s)
+	}
+	tm.replay(":fc1", "\r")
+	expectStatus(t, scr, "user eq ***")
+	if strings.Contains(scr.String(), "bob") {
+		t.Errorf("filter mask should be masked:\n%s", scr)
+	}
+	tm.replay("R")
+	tm.showPosition()
+	expectStatus(t, scr, "user eq bob")
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// sgrSequence - ANSI sequence setting colors and text attributes (kept for trusted sources, see option ansi)
+var sgrSequence = regexp.MustCompile(`^\x1b\[[0-9;]*m`)
+
+// csiSequence - ANSI control sequence (colors, cursor movement); it takes no place on the screen
+var csiSequence = regexp.MustCompile(`^\x1b\[[0-9;?]*[@-~]`)
+
+// sanitizer replaces control characters of log values so they can not affect the terminal
+type sanitizer struct {
+	// hex - show control characters as \xNN instead of ^X
+	hex bool
+	// ansi - keep color sequences (for trusted sources)
+	ansi bool
+}
+
+// String returns s with control characters escaped (^[ or \x1b); tabs are replaced by space
+func (sn sanitizer) String(s string) string {
+	if !needsSanitize(s) {
+		return s
+	}
+	b := strings.Builder{}
+	for i := 0; i < len(s); {
+		if sn.ansi && s[i] == keyEsc {
+			if seq := sgrSequence.FindString(s[i:]); seq != "" {
+				b.WriteString(seq)
+				i += len(seq)
+				continue
+			}
+		}
+		r, n := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && n == 1:
+			b.WriteString(fmt.Sprintf("\\x%02x", s[i]))
+		case r == '\t':
+			b.WriteByte(' ')
+		case r < ' ' || r == 0x7f:
+			if sn.hex {
+				b.WriteString(fmt.Sprintf("\\x%02x", r))
+			} else {
+				b.WriteByte('^')
+				b.WriteByte(byte(r) ^ 0x40)
+			}
+		case r >= 0x80 && r < 0xa0:
+			// C1 controls (0x9b is CSI too)
+			b.WriteString(fmt.Sprintf("\\u%04x", r))
+		default:
+			b.WriteString(s[i : i+n])
+		}
+		i += n
+	}
+	return b.String()
+}
+
+func needsSanitize(s string) bool {
+	for _, r := range s {
+		if r < ' ' || r == 0x7f || r >= 0x80 && r < 0xa0 || r == utf8.RuneError {
+			return true
+		}
+	}
+	return false
+}
+
+// wideRanges - East Asian wide and fullwidth characters taking two screen cells
+var wideRanges = [][2]rune{
+	{0x1100, 0x115f},
+	{0x2e80, 0x303e},
+	{0x3041, 0x33ff},
+	{0x3400, 0x4dbf},
+	{0x4e00, 0x9fff},
+	{0xa000, 0xa4cf},
+	{0xac00, 0xd7a3},
+	{0xf900, 0xfaff},
+	{0xfe30, 0xfe4f},
+	{0xff00, 0xff60},
+	{0xffe0, 0xffe6},
+	{0x1f300, 0x1f64f},
+	{0x1f900, 0x1f9ff},
+	{0x20000, 0x3fffd},
+}
+
+// runeWidth returns number of screen cells of r
+func runeWidth(r rune) int {
+	if r < wideRanges[0][0] {
+		return 1
+	}
+	for _, rg := range wideRanges {
+		if r >= rg[0] && r <= rg[1] {
+			return 2
+		}
+	}
+	return 1
+}
+
+// width returns number of screen cells of s (control sequences take no cells)
+func width(s string) int {
+	w := 0
+	for i := 0; i < len(s); {
+		if s[i] == keyEsc {
+			if seq := csiSequence.FindString(s[i:]); seq != "" {
+				i += len(seq)
+				continue
+			}
+		}
+		r, n := utf8.DecodeRuneInString(s[i:])
+		i += n
+		w += runeWidth(r)
+	}
+	return w
+}
+
+// truncate returns beginning of s taking no more than w screen cells; control sequences are kept whole
+func truncate(s string, w int) string {
+	for i := 0; i < len(s); {
+		if s[i] == keyEsc {
+			if seq := csiSequence.FindString(s[i:]); seq != "" {
+				i += len(seq)
+				continue
+			}
+		}
+		r, n := utf8.DecodeRuneInString(s[i:])
+		if rw := runeWidth(r); rw <= w {
+			w -= rw
+		} else {
+			return s[:i]
+		}
+		i += n
+	}
+	return s
+}
+
+// tail returns end of s taking no more than w screen cells (s should not contain control sequences)
+func tail(s string, w int) string {
+	i := len(s)
+	for i > 0 {
+		r, n := utf8.DecodeLastRuneInString(s[:i])
+		if rw := runeWidth(r); rw <= w {
+			w -= rw
+		} else {
+			break
+		}
+		i -= n
+	}
+	return s[i:]
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizer(t *testing.T) {
+	for _, c := range []struct {
+		sn       sanitizer
+		s        string
+		expected string
+	}{
+		{sanitizer{}, "plain text", "plain text"},
+		{sanitizer{}, "a\x1b[2Jb\tc\x7f", "a^[[2Jb c^?"},
+		{sanitizer{hex: true}, "a\x1b[2Jb", `a\x1b[2Jb`},
+		{sanitizer{}, "csi\u009b2J", `csi\u009b2J`},
+		{sanitizer{}, "bad\xffutf", `bad\xffutf`},
+		{sanitizer{ansi: true}, "\x1b[31mred\x1b[0m\x1b[2J", "\x1b[31mred\x1b[0m^[[2J"},
+	} {
+		if s := c.sn.String(c.s); s != c.expected {
+			t.Errorf("%q: expected %q, got %q", c.s, c.expected, s)
+		}
+	}
+}
+
+func TestWidth(t *testing.T) {
+	s := "\x1b[31m×ab\x1b[0mcd"
+	if w := width(s); w != 5 {
+		t.Errorf("width of %q should be 5, got %d", s, w)
+	}
+	if tr := truncate(s, 3); tr != "\x1b[31m×ab\x1b[0m" {
+		t.Errorf("truncate should count cells and keep sequences: %q", tr)
+	}
+	if tr := truncate("abc", 5); tr != "abc" {
+		t.Errorf("short string should be kept: %q", tr)
+	}
+	if tl := tail("a×bc", 3); tl != "×bc" {
+		t.Errorf("tail should count runes: %q", tl)
+	}
+	if w := width("日本語"); w != 6 {
+		t.Errorf("wide characters should take two cells, got %d", w)
+	}
+	if tr := truncate("日本語", 5); tr != "日本" {
+		t.Errorf("truncate should not split wide character: %q", tr)
+	}
+	if tl := tail("a日本", 3); tl != "本" {
+		t.Errorf("tail should count wide characters: %q", tl)
+	}
+}
+
+func TestSanitizedScreen(t *testing.T) {
+	resetConfig("ansi", true)
+	tm, scr := newTestTerm(t, 40, 8, []string{
+		`{"time": "2020-01-01T00:00:00", "level": "info", "msg": "\u001b[31mred\u001b[0m and a long tail of text", "trace_id": "t\u001b[2J", "obj": {"k": "x\u007f\u009by"}}`,
+		`{"time": "2020-01-01T00:00:01", "level": "info", "msg": "b", "trace_id": "t\u001b[2J"}`,
+	})
+	// color sequences take no place
+	expectLines(t, scr, "2020-01-01T00:00:00  info red and a long", "2020-01-01T00:00:01  info b; trace_id: t")
+	tm.replay("c")
+	expectStatus(t, scr, "trace_id follow t^[[2J")
+	tm.replay("\r")
+	if s := scr.String(); !strings.Contains(s, `x^?\u009by`) {
+		t.Errorf("nested values should be sanitized:\n%s", s)
+	}
+	tm.replay("q", ":filters", "\r")
+	if l := scr.Line(3); !strings.HasPrefix(l, "  1 trace_id follow t^[[2J") {
+		t.Errorf("filters panel should be sanitized: %q\n%s", l, scr)
+	}
+	tm.replay("\x1b", ":f/", "\t")
+	if s := scr.Line(scr.h); !strings.Contains(s, "trace_id") || strings.Contains(s, "\x1b") {
+		t.Errorf("tags in options should be sanitized: %q", s)
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// screen - backend term draws on and reads keys from
+type screen interface {
+	io.ReadWriter
+	// Size returns screen width and height in cells
+	Size() (w, h int, err error)
+}
+
+// ttyScreen - screen on real terminal
+type ttyScreen struct {
+	f *os.File
+}
+
+func (s *ttyScreen) Read(p []byte) (int, error) {
+	return s.f.Read(p)
+}
+
+func (s *ttyScreen) Write(p []byte) (int, error) {
+	return s.f.Write(p)
+}
+
+func (s *ttyScreen) Size() (int, int, error) {
+	return terminal.GetSize(int(s.f.Fd()))
+}
+
+type cell struct {
+	ch   rune
+	fg   int
+	bg   int
+	bold bool
+}
+
+// virtualScreen - in-memory screen which interprets escape sequences written by term into cells grid
+//
+//	used for headless rendering (tests, dumps)
+type virtualScreen struct {
+	w, h  int
+	cells [][]cell
+	row   int
+	col   int
+	fg    int
+	bg    int
+	bold  bool
+	input [][]byte
+	rest  []byte
+}
+
+func newVirtualScreen(w, h int) *virtualScreen {
+	s := &virtualScreen{}
+	s.SetSize(w, h)
+	return s
+}
+
+// SetSize changes screen geometry keeping as much content as fits
+func (s *virtualScreen) SetSize(w, h int) {
+	cells := make([][]cell, h)
+	for r := range cells {
+		cells[r] = make([]cell, w)
+		for c := range cells[r] {
+			if r < s.h && c < s.w {
+				cells[r][c] = s.cells[r][c]
+			} else {
+				cells[r][c] = s.blank()
+			}
+		}
+	}
+	s.w, s.h, s.cells = w, h, cells
+	if s.row >= h {
+		s.row = h - 1
+	}
+	if s.col >= w {
+		s.col = w - 1
+	}
+}
+
+func (s *virtualScreen) Size() (int, int, error) {
+	return s.w, s.h, nil
+}
+
+// Type queues keys to be returned by Read
+func (s *virtualScreen) Type(keys ...string) {
+	for _, k := range keys {
+		s.input = append(s.input, []byte(k))
+	}
+}
+
+func (s *virtualScreen) Read(p []byte) (int, error) {
+	if len(s.input) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, s.input[0])
+	if n == len(s.input[0]) {
+		s.input = s.input[1:]
+	} else {
+		s.input[0] = s.input[0][n:]
+	}
+	return n, nil
+}
+
+func (s *virtualScreen) Write(p []byte) (int, error) {
+	b := append(s.rest, p...)
+	s.rest = nil
+	for len(b) > 0 {
+		if b[0] == keyEsc {
+			n := s.escape(b)
+			if n == 0 {
+				// incomplete sequence; wait for the rest
+				s.rest = append([]byte{}, b...)
+				break
+			}
+			b = b[n:]
+			continue
+		}
+		r, n := utf8.DecodeRune(b)
+		if r == utf8.RuneError && !utf8.FullRune(b) {
+			s.rest = append([]byte{}, b...)
+			break
+		}
+		b = b[n:]
+		s.put(r)
+	}
+	return len(p), nil
+}
+
+// Cell returns cell at (1-based) row and column
+func (s *virtualScreen) Cell(row, col int) cell {
+	if row < 1 || row > s.h || col < 1 || col > s.w {
+		return cell{}
+	}
+	return s.cells[row-1][col-1]
+}
+
+// Line returns text of (1-based) row with trailing spaces trimmed
+func (s *virtualScreen) Line(row int) string {
+	if row < 1 || row > s.h {
+		return ""
+	}
+	b := strings.Builder{}
+	for _, c := range s.cells[row-1] {
+		b.WriteRune(c.ch)
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+// String returns whole screen as text, one line per row
+func (s *virtualScreen) String() string {
+	lines := make([]string, s.h)
+	for r := range lines {
+		lines[r] = s.Line(r + 1)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (s *virtualScreen) blank() cell {
+	return cell{ch: ' ', fg: s.fg, bg: s.bg}
+}
+
+func (s *virtualScreen) put(r rune) {
+	switch r {
+	case '\r':
+		s.col = 0
+		return
+	case '\n':
+		s.newLine()
+		return
+	case '\t':
+		s.col = (s.col/8 + 1) * 8
+		if s.col >= s.w {
+			s.col = s.w - 1
+		}
+		return
+	}
+	if r < ' ' {
+		return
+	}
+	if s.col >= s.w {
+		s.col = 0
+		s.newLine()
+	}
+	s.cells[s.row][s.col] = cell{ch: r, fg: s.fg, bg: s.bg, bold: s.bold}
+	s.col++
+}
+
+func (s *virtualScreen) newLine() {
+	if s.row == s.h-1 {
+		s.scroll(1)
+		return
+	}
+	s.row++
+}
+
+// scroll scrolls screen n lines up (negative n scrolls down)
+func (s *virtualScreen) scroll(n int) {
+	for ; n > 0; n-- {
+		copy(s.cells, s.cells[1:])
+		s.cells[s.h-1] = s.blankLine()
+	}
+	for ; n < 0; n++ {
+		copy(s.cells[1:], s.cells)
+		s.cells[0] = s.blankLine()
+	}
+}
+
+func (s *virtualScreen) blankLine() []cell {
+	l := make([]cell, s.w)
+	for i := range l {
+		l[i] = s.blank()
+	}
+	return l
+}
+
+func clamp(v, min, max int) int {
+	if v > max {
+		v = max
+	}
+	if v < min {
+		v = min
+	}
+	return v
+}
+
+// escape interprets CSI sequence at the beginning of b and returns its length or 0 if it is incomplete
+func (s *virtualScreen) escape(b []byte) int {
+	if len(b) < 2 {
+		return 0
+	}
+	if b[1] != '[' {
+		return 2
+	}
+	end := 2
+	for end < len(b) && (b[end] < 0x40 || b[end] > 0x7e) {
+		end++
+	}
+	if end == len(b) {
+		return 0
+	}
+	params := string(b[2:end])
+	if strings.HasPrefix(params, "?") {
+		// private modes (alternate screen and so on) do not change cells
+		return end + 1
+	}
+	args := []int{}
+	for _, p := range strings.Split(params, ";") {
+		a, _ := strconv.Atoi(p)
+		args = append(args, a)
+	}
+	arg := func(i, def int) int {
+		if i < len(args) && args[i] != 0 {
+			return args[i]
+		}
+		return def
+	}
+	switch b[end] {
+	case 'H':
+		s.row, s.col = clamp(arg(0, 1)-1, 0, s.h-1), clamp(arg(1, 1)-1, 0, s.w-1)
+	case 'J':
+		if arg(0, 0) == 2 {
+			for r := range s.cells {
+				s.cells[r] = s.blankLine()
+			}
+		}
+	case 'K':
+		from, to := s.col, s.w
+		switch arg(0, 0) {
+		case 1:
+			from, to = 0, s.col+1
+		case 2:
+			from = 0
+		}
+		for c := from; c < to && c < s.w; c++ {
+			s.cells[s.row][c] = s.blank()
+		}
+	case 'S':
+		s.scroll(arg(0, 1))
+	case 'T':
+		s.scroll(-arg(0, 1))
+	case 'm':
+		for _, a := range args {
+			switch {
+			case a == 0:
+				s.fg, s.bg, s.bold = 0, 0, false
+			case a == 1:
+				s.bold = true
+			case a == fgDefault:
+				s.fg = 0
+			case a == bgDefault:
+				s.bg = 0
+			case a >= fgBlack && a < fgDefault:
+				s.fg = a
+			case a >= bgBlack && a < bgDefault:
+				s.bg = a
+			}
+		}
+	}
+	return end + 1
+}
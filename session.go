@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// viewStep - how view is made from its parent
+type viewStep struct {
+	Filter  *Filter     `json:"filter,omitempty"`
+	Follow  *followStep `json:"follow,omitempty"`
+	Context int         `json:"context,omitempty"`
+	SetOp   *setOpStep  `json:"setOp,omitempty"`
+	Sort    *sortStep   `json:"sort,omitempty"`
+	Dedup   *dedupStep  `json:"dedup,omitempty"`
+	// Disabled - view is the same as parent
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+type followStep struct {
+	Tag   string `json:"tag"`
+	Value string `json:"value"`
+}
+
+// apply makes view from parent v
+func (s viewStep) apply(v *FileView) (*FileView, error) {
+	var ret *FileView
+	switch {
+	case s.Disabled:
+		return v.passThrough(s), nil
+	case s.Filter != nil:
+		ret = v.Filter(*s.Filter)
+	case s.Follow != nil:
+		ret = v.Follow(s.Follow.Tag, s.Follow.Value)
+	case s.Sort != nil:
+		ret = v.Sort(s.Sort.Tag, s.Sort.Desc)
+	case s.Dedup != nil:
+		ret = v.Collapse(s.Dedup.Tags, s.Dedup.Global)
+	case s.SetOp != nil:
+		root := v
+		for root.parent != nil {
+			root = root.parent
+		}
+		operands := []*FileView{}
+		for _, steps := range s.SetOp.Operands {
+			o, err := makeViews(root, steps)
+			if err != nil {
+				return v, err
+			}
+			operands = append(operands, o)
+		}
+		var err error
+		if ret, err = v.SetOp(s.SetOp.Op, s.SetOp.Names, operands...); err != nil {
+			return v, err
+		}
+	default:
+		return v, errors.New("empty view step")
+	}
+	if s.Context > 0 {
+		if err := ret.SetContext(s.Context); err != nil {
+			return ret, err
+		}
+	}
+	return ret, nil
+}
+
+// String returns description of the step
+func (s viewStep) String() string {
+	ret := ""
+	switch {
+	case s.Filter != nil:
+		ret = s.Filter.String()
+	case s.Follow != nil:
+		ret = fmt.Sprintf("%s follow %s", s.Follow.Tag, s.Follow.Value)
+	case s.SetOp != nil:
+		ret = fmt.Sprintf("%s %s", s.SetOp.Op, strings.Join(s.SetOp.Names, " "))
+	case s.Dedup != nil:
+		ret = "collapse"
+		if s.Dedup.Global {
+			ret = "dedup"
+		}
+		if len(s.Dedup.Tags) > 0 {
+			ret += " " + strings.Join(s.Dedup.Tags, ",")
+		}
+	case s.Sort != nil:
+		ret = fmt.Sprintf("sort %s", s.Sort.Tag)
+		if s.Sort.Desc {
+			ret += " desc"
+		}
+	}
+	if s.Context > 0 {
+		ret += fmt.Sprintf(" (context %d)", s.Context)
+	}
+	return ret
+}
+
+// passThrough returns view with the same lines as f made by disabled step s
+func (f *FileView) passThrough(s viewStep) *FileView {
+	ret := &FileView{parent: f, file: f.file, name: f.name, unordered: f.unordered, depth: f.depth, step: s, matches: f.matches, context: f.context}
+	if f.index != nil {
+		ret.index = append([]int{}, f.index...)
+	} else {
+		ret.index = make([]int, f.len())
+		for i := range ret.index {
+			ret.index[i] = i
+		}
+	}
+	if f.parent == nil {
+		ret.name = ""
+	}
+	return ret
+}
+
+// Steps returns steps making the view from the root one
+func (f *FileView) Steps() []viewStep {
+	steps := []viewStep{}
+	for v := f; v.parent != nil; v = v.parent {
+		steps = append([]viewStep{v.step}, steps...)
+	}
+	return steps
+}
+
+// session - state of investigation which may be saved and restored
+type session struct {
+	Files     []string       `json:"files"`
+	Format    string         `json:"format,omitempty"`
+	AttachRaw bool           `json:"attachRaw,omitempty"`
+	Views     []viewStep     `json:"views"`
+	Position  int            `json:"position"`
+	Current   int            `json:"current"`
+	Marks     map[string]int `json:"marks,omitempty"`
+	Columns   []string       `json:"columns,omitempty"`
+	Let       []string       `json:"let,omitempty"`
+	Search    *sessionSearch `json:"search,omitempty"`
+}
+
+type sessionSearch struct {
+	Mask     string          `json:"mask"`
+	Idx      int             `json:"idx"`
+	Dir      SearchDirection `json:"dir"`
+	Tag      string          `json:"tag,omitempty"`
+	IsRegexp bool            `json:"isRegexp,omitempty"`
+}
+
+// sessionFile returns path of the session: config option sessions.dir or jlv/sessions in user's state dir
+func sessionFile(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid session name: %q", name)
+	}
+	dir := viper.GetString("sessions.dir")
+	if dir == "" {
+		dir = filepath.Join(filepath.Dir(historyFile()), "sessions")
+	}
+	return filepath.Join(dir, name+".json"), nil
+}
+
+func loadSession(name string) (*session, error) {
+	fn, err := sessionFile(name)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	s := &session{}
+	if err = json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("session %s: %v", name, err)
+	}
+	if len(s.Files) == 0 {
+		return nil, fmt.Errorf("session %s: no files", name)
+	}
+	return s, nil
+}
+
+func (s *session) save(name string) error {
+	fn, err := sessionFile(name)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(fn), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fn, b, 0600)
+}
+
+// session returns current state of the term
+func (t *term) session() *session {
+	file := t.root.file
+	path, err := filepath.Abs(file.f.Name())
+	if err != nil {
+		path = file.f.Name()
+	}
+	s := &session{
+		Files:     []string{path},
+		Format:    file.format,
+		AttachRaw: file.AttachRaw(),
+		Views:     t.f.Steps(),
+		Position:  t.f.Position(),
+		Current:   t.current,
+		Marks:     map[string]int{},
+		Columns:   t.columns,
+		Let:       file.Computed(),
+	}
+	for name, ln := range t.marks {
+		s.Marks[string(name)] = ln
+	}
+	if t.lastSearch.mask != "" {
+		ls := t.lastSearch
+		s.Search = &sessionSearch{Mask: ls.mask, Idx: ls.idx, Dir: ls.dir, Tag: ls.tag, IsRegexp: ls.isRegexp}
+	}
+	return s
+}
+
+// restore rebuilds computed tags, views, position, marks, columns and last search of the session;
+// the session should be saved for the file of the term
+func (t *term) restore(s *session) error {
+	if len(s.Files) == 0 || s.Files[0] != t.session().Files[0] {
+		return fmt.Errorf("session is for file %s", strings.Join(s.Files, ", "))
+	}
+	t.root.file.ResetComputed()
+	for _, l := range s.Let {
+		name, src, err := parseBinding(l)
+		if err == nil {
+			err = t.root.file.SetComputed(name, src)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	v := t.root
+	for _, step := range s.Views {
+		var err error
+		if v, err = step.apply(v); err != nil {
+			return err
+		}
+	}
+	t.f = v
+	t.f.SetPosition(s.Position)
+	t.current = s.Current
+	t.clampPosition()
+	for name, ln := range s.Marks {
+		if len(name) == 1 && isMarkName(name[0]) {
+			t.marks[name[0]] = ln
+		}
+	}
+	if s.Columns != nil {
+		t.columns = s.Columns
+	}
+	if s.Search != nil {
+		ls := s.Search
+		t.lastSearch = searchParams{mask: ls.Mask, idx: ls.Idx, dir: ls.Dir, tag: ls.Tag, isRegexp: ls.IsRegexp}
+	}
+	return nil
+}
+
+// sessionCommandExecute processes :session save <name> and :session load <name>
+func sessionCommandExecute(t *term) {
+	args := strings.Fields(strings.TrimPrefix(t.command, ":session"))
+	if len(args) != 2 || args[0] != "save" && args[0] != "load" {
+		t.message = "usage: :session save|load <name>"
+		return
+	}
+	if args[0] == "save" {
+		if err := t.session().save(args[1]); err != nil {
+			t.message = err.Error()
+			return
+		}
+		t.message = fmt.Sprintf("session %s is saved", args[1])
+		return
+	}
+	s, err := loadSession(args[1])
+	if err == nil {
+		marks := t.marks
+		t.marks = map[byte]int{}
+		if err = t.restore(s); err != nil {
+			t.marks = marks
+		}
+	}
+	if err != nil {
+		t.message = err.Error()
+		return
+	}
+	t.redraw()
+}
+
+func sessionCommandOptions(t *term) {
+	t.command = ":session "
+	t.options = newOptions("save", "save ", "load", "load ")
+}
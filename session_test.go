@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestSessionRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jlv-sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	resetConfig("filters", []string{"level/error"}, "sessions.dir", dir)
+	lines := records(20)
+	tm, scr := newFilteredTestTerm(t, 80, 6, lines)
+	tm.replay(":f/n/1/$", "\r", keyDown, "ma", ":session save s", "\r")
+	expectStatus(t, scr, "session s is saved")
+	s, err := loadSession("s")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same file opened with the default filters
+	tm.f = tm.root
+	tm.marks = map[byte]int{}
+	if err := tm.restore(s); err != nil {
+		t.Fatal(err)
+	}
+	if n, st := tm.f.LinesCount(), len(tm.f.Steps()); n != 3 || st != 2 {
+		t.Errorf("restored view should have 3 records and 2 filters, got %d records and %d filters", n, st)
+	}
+	if tm.marks['a'] != 14 {
+		t.Errorf("mark a should be restored, got %v", tm.marks)
+	}
+
+	other, _ := newFilteredTestTerm(t, 80, 6, lines)
+	if err := other.restore(s); err == nil {
+		t.Error("session of another file should not be restored")
+	}
+	other.replay(":session load s", "\r")
+	if len(other.f.Steps()) != 1 {
+		t.Errorf(":session load of another file should keep the view, got %v", other.f.Steps())
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// set operations on views
+const (
+	SetUnion     = "union"
+	SetIntersect = "intersect"
+	SetMinus     = "minus"
+)
+
+// viewAll - name of the root view for set operations
+const viewAll = "all"
+
+// setOpStep - view made by set operation on views made by Operands steps from the root view
+type setOpStep struct {
+	Op       string       `json:"op"`
+	Names    []string     `json:"names"`
+	Operands [][]viewStep `json:"operands"`
+}
+
+// sortedLines returns file's lines of the view in file order (without separators and context lines)
+func (f *FileView) sortedLines() []int {
+	if f.matches != nil {
+		return append([]int(nil), f.matches...)
+	}
+	if f.index == nil {
+		lines := make([]int, f.len())
+		for i := range lines {
+			lines[i] = i
+		}
+		return lines
+	}
+	lines := make([]int, 0, len(f.index))
+	for _, l := range f.index {
+		if l != lineSeparator {
+			lines = append(lines, l)
+		}
+	}
+	if f.unordered {
+		sort.Ints(lines)
+	}
+	return lines
+}
+
+// SetOp returns view with lines of operands combined by op (union, intersect or minus);
+//
+//	minus returns lines of the first operand which are not in any other one
+func (f *FileView) SetOp(op string, names []string, operands ...*FileView) (*FileView, error) {
+	if len(operands) < 2 {
+		return nil, fmt.Errorf("%s: at least two views are required", op)
+	}
+	lines := operands[0].sortedLines()
+	for _, o := range operands[1:] {
+		switch op {
+		case SetUnion:
+			lines = mergeUnion(lines, o.sortedLines())
+		case SetIntersect:
+			lines = mergeIntersect(lines, o.sortedLines())
+		case SetMinus:
+			lines = mergeMinus(lines, o.sortedLines())
+		default:
+			return nil, fmt.Errorf("unknown set operation: %s", op)
+		}
+	}
+	step := viewStep{SetOp: &setOpStep{Op: op, Names: names}}
+	for _, o := range operands {
+		step.SetOp.Operands = append(step.SetOp.Operands, o.Steps())
+	}
+	return &FileView{parent: f, file: f.file, name: fmt.Sprintf("%s %s", op, strings.Join(names, " ")), index: lines, step: step}, nil
+}
+
+func mergeUnion(a, b []int) []int {
+	ret := make([]int, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			ret = append(ret, a[i])
+			i++
+		case a[i] > b[j]:
+			ret = append(ret, b[j])
+			j++
+		default:
+			ret = append(ret, a[i])
+			i++
+			j++
+		}
+	}
+	ret = append(ret, a[i:]...)
+	return append(ret, b[j:]...)
+}
+
+func mergeIntersect(a, b []int) []int {
+	ret := []int{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			ret = append(ret, a[i])
+			i++
+			j++
+		}
+	}
+	return ret
+}
+
+func mergeMinus(a, b []int) []int {
+	ret := []int{}
+	i, j := 0, 0
+	for i < len(a) {
+		for j < len(b) && b[j] < a[i] {
+			j++
+		}
+		if j == len(b) || b[j] != a[i] {
+			ret = append(ret, a[i])
+		}
+		i++
+	}
+	return ret
+}
+
+// makeViews rebuilds views from the root one
+func makeViews(root *FileView, steps []viewStep) (*FileView, error) {
+	v := root
+	for _, s := range steps {
+		var err error
+		if v, err = s.apply(v); err != nil {
+			return v, err
+		}
+	}
+	return v, nil
+}
+
+// namedView returns view by name ("all" is the root view)
+func (t *term) namedView(name string) (*FileView, bool) {
+	if name == viewAll {
+		return t.root, true
+	}
+	v, ok := t.views[name]
+	return v, ok
+}
+
+// viewCommandExecute processes :view name <name>, :view list, :view goto <name> and
+// :view union|intersect|minus <name> <name>...
+func viewCommandExecute(t *term) {
+	args := strings.Fields(strings.TrimPrefix(t.command, ":view"))
+	if len(args) == 0 {
+		t.message = "usage: :view name <name>|list|goto <name>|union|intersect|minus <name> <name>..."
+		return
+	}
+	switch args[0] {
+	case "name":
+		if len(args) != 2 || args[1] == viewAll {
+			t.message = "usage: :view name <name> (all is reserved)"
+			return
+		}
+		t.views[args[1]] = t.f
+		t.message = fmt.Sprintf("view %s: %s", args[1], t.f.Name())
+	case "list":
+		if len(t.views) == 0 {
+			t.message = "no named views"
+			return
+		}
+		names := []string{}
+		for n := range t.views {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		t.options = &options{replace: true}
+		for _, n := range names {
+			t.options.add(fmt.Sprintf("%s(%d)", n, t.views[n].LinesCount()), ":view goto "+n)
+		}
+	case "goto":
+		v, ok := t.namedView(strings.Join(args[1:], " "))
+		if !ok {
+			t.message = fmt.Sprintf("%s: no such view", strings.Join(args[1:], " "))
+			return
+		}
+		before := t.f.Steps()
+		t.f = v
+		t.clampPosition()
+		t.saveUndo(before)
+		t.redraw()
+	case SetUnion, SetIntersect, SetMinus:
+		operands := []*FileView{}
+		for _, n := range args[1:] {
+			v, ok := t.namedView(n)
+			if !ok {
+				t.message = fmt.Sprintf("%s: no such view", n)
+				return
+			}
+			operands = append(operands, v)
+		}
+		v, err := t.f.SetOp(args[0], args[1:], operands...)
+		if err != nil {
+			t.message = err.Error()
+			return
+		}
+		before := t.f.Steps()
+		t.f = v
+		t.saveUndo(before)
+		t.home()
+	default:
+		t.message = fmt.Sprintf("%s: unknown view command", args[0])
+	}
+}
+
+func viewCommandOptions(t *term) {
+	args := strings.Fields(strings.TrimPrefix(t.command, ":view"))
+	if len(args) == 0 {
+		t.command = ":view "
+		t.options = newOptions("name", "name ", "list", "list", "goto", "goto ", SetUnion, SetUnion+" ", SetIntersect, SetIntersect+" ", SetMinus, SetMinus+" ")
+		return
+	}
+	names := []string{viewAll}
+	for n := range t.views {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	if !strings.HasSuffix(t.command, " ") {
+		t.command += " "
+	}
+	t.options = newOptionsFromArray(names, false)
+	for i := range t.options.options {
+		t.options.options[i].command += " "
+	}
+}
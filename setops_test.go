@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestViewSetOps(t *testing.T) {
+	resetConfig()
+	tm, scr := newTestTerm(t, 80, 8, records(8))
+	tm.replay(":f/level/error", "\r", ":view name e", "\r")
+	expectStatus(t, scr, "view e: ")
+	tm.replay(":view goto all", "\r", ":f/level/warn", "\r", ":view name w", "\r")
+	tm.replay(":view union e w", "\r")
+	expectLines(t, scr, "2020-01-01T00:00:01", "2020-01-01T00:00:02", "2020-01-01T00:00:05", "2020-01-01T00:00:06", "")
+	tm.replay(":view intersect all e", "\r")
+	expectLines(t, scr, "2020-01-01T00:00:02", "2020-01-01T00:00:06", "")
+	tm.replay(":view minus all e w", "\r")
+	expectLines(t, scr, "2020-01-01T00:00:00", "2020-01-01T00:00:03", "2020-01-01T00:00:04", "2020-01-01T00:00:07", "")
+	tm.replay(":view union e x", "\r")
+	expectStatus(t, scr, "x: no such view")
+	tm.replay(":view list", "\r")
+	if tm.options == nil || len(tm.options.options) != 2 {
+		t.Fatalf("expected options of two views, got %v", tm.options)
+	}
+	// context lines are not members of the view
+	tm.replay("\x1b", ":view goto e", "\r", ":fc1", "\r", ":view name c", "\r")
+	tm.replay(":view union c w", "\r")
+	expectLines(t, scr, "2020-01-01T00:00:01", "2020-01-01T00:00:02", "2020-01-01T00:00:05", "2020-01-01T00:00:06", "")
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// sortStep - view ordered by Tag
+type sortStep struct {
+	Tag  string `json:"tag"`
+	Desc bool   `json:"desc,omitempty"`
+}
+
+// Sort returns view with lines of f ordered by tag's value (stable; records without the tag go first in ascending order);
+// context lines are skipped as Filter does
+func (f *FileView) Sort(tag string, desc bool) *FileView {
+	dir := "asc"
+	if desc {
+		dir = "desc"
+	}
+	ret := &FileView{parent: f, file: f.file, name: fmt.Sprintf("sort %s %s", tag, dir), unordered: true}
+	ret.step = viewStep{Sort: &sortStep{Tag: tag, Desc: desc}}
+	lines := f.sortedLines()
+	if f.unordered {
+		// keep order of the parent for ties
+		lines = lines[:0]
+		for _, l := range f.index {
+			if l != lineSeparator {
+				lines = append(lines, l)
+			}
+		}
+	}
+	keys := make([]valueKey, len(lines))
+	for i, l := range lines {
+		v, ok := lookupTag(f.file.Line(l), tag)
+		if !ok {
+			v = nil
+		}
+		keys[i] = newValueKey(v)
+	}
+	order := make([]int, len(lines))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		c := keys[order[i]].compare(keys[order[j]])
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+	ret.index = make([]int, len(lines))
+	for i, o := range order {
+		ret.index[i] = lines[o]
+	}
+	return ret
+}
+
+// sortCommandExecute processes :sort <tag> [asc|desc]
+func sortCommandExecute(t *term) {
+	args := strings.Fields(strings.TrimPrefix(t.command, ":sort"))
+	if len(args) == 0 || len(args) > 2 || len(args) == 2 && args[1] != "asc" && args[1] != "desc" {
+		t.message = "usage: :sort <tag> [asc|desc]"
+		return
+	}
+	ln := t.f.FileLine(t.f.Position() + t.current)
+	defer t.saveUndo(t.f.Steps())
+	t.f = t.f.Sort(args[0], len(args) == 2 && args[1] == "desc")
+	idx, _ := t.f.ViewLine(ln)
+	t.goToLine(idx + 1)
+}
+
+func sortCommandOptions(t *term) {
+	args := strings.Fields(strings.TrimPrefix(t.command, ":sort"))
+	if len(args) == 0 {
+		t.command = ":sort "
+		t.options = newOptionsFromArray(t.f.KnownTags(), false)
+		return
+	}
+	t.command = fmt.Sprintf(":sort %s ", args[0])
+	t.options = newOptions("asc", "asc", "desc", "desc")
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSort(t *testing.T) {
+	resetConfig()
+	tm, scr := newTestTerm(t, 80, 6, []string{
+		`{"time": "2020-01-01T00:00:00", "level": "info", "msg": "a", "ms": 20}`,
+		`{"time": "2020-01-01T00:00:01", "level": "info", "msg": "b", "ms": 3}`,
+		`{"time": "2020-01-01T00:00:02", "level": "info", "msg": "c"}`,
+		`{"time": "2020-01-01T00:00:03", "level": "info", "msg": "d", "ms": 100}`,
+		`{"time": "2020-01-01T00:00:04", "level": "info", "msg": "e", "ms": 3}`,
+	})
+	// records without the tag go first
+	tm.replay(":sort ms", "\r")
+	expectLines(t, scr,
+		"2020-01-01T00:00:02  info c",
+		"2020-01-01T00:00:01  info b",
+		"2020-01-01T00:00:04  info e",
+		"2020-01-01T00:00:00  info a",
+		"2020-01-01T00:00:03  info d",
+	)
+	tm.replay(":fu", "\r", ":sort ms desc", "\r")
+	expectLines(t, scr,
+		"2020-01-01T00:00:03  info d",
+		"2020-01-01T00:00:00  info a",
+		"2020-01-01T00:00:01  info b",
+		"2020-01-01T00:00:04  info e",
+		"2020-01-01T00:00:02  info c",
+	)
+	tm.replay(":fu", "\r")
+	expectLines(t, scr, "2020-01-01T00:00:00  info a", "2020-01-01T00:00:01  info b")
+	tm.replay(":sort", "\r")
+	expectStatus(t, scr, "usage")
+}
+
+func TestSortSkipsContext(t *testing.T) {
+	resetConfig()
+	tm, scr := newTestTerm(t, 80, 8, records(8))
+	tm.replay(":f/level/error", "\r", ":fc1", "\r", ":sort n desc", "\r")
+	expectLines(t, scr, "2020-01-01T00:00:06", "2020-01-01T00:00:02", "")
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// SplitMode - how file is split to records
+type SplitMode string
+
+const (
+	// SplitAuto - detect mode by the beginning of the file
+	SplitAuto SplitMode = "auto"
+	// SplitLines - one record per line
+	SplitLines SplitMode = "lines"
+	// SplitValues - one record per json value whatever the whitespace (pretty-printed or concatenated json)
+	SplitValues SplitMode = "values"
+)
+
+const detectSize = 64 * 1024
+
+// detectSplit chooses SplitValues if the first not empty line starts json value but is not valid json itself
+func detectSplit(f *os.File) SplitMode {
+	f.Seek(0, 0)
+	defer f.Seek(0, 0)
+	s := bufio.NewScanner(io.LimitReader(f, detectSize))
+	s.Buffer(make([]byte, bufSize), detectSize)
+	for s.Scan() {
+		b := bytes.TrimSpace(s.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		if (b[0] == '{' || b[0] == '[') && !json.Valid(b) {
+			return SplitValues
+		}
+		return SplitLines
+	}
+	if s.Err() == bufio.ErrTooLong {
+		// the first line is longer than detectSize: most likely concatenated records
+		return SplitValues
+	}
+	return SplitLines
+}
+
+// valueSplitter - state of json values scanner
+type valueSplitter struct {
+	f      *File
+	record int
+	// start - offset of the current value or raw line; -1 if between them
+	start int64
+	// buf - bytes of the current value (to validate it and to rescan it if it is not valid)
+	buf   []byte
+	depth int
+	inStr bool
+	esc   bool
+	raw   bool
+	// lineStart - the previous char is new line
+	lineStart bool
+}
+
+// indexValues splits file to records by tracking braces depth and strings;
+//
+//	text outside of values, arrays and invalid values are split to raw lines
+func (f *File) indexValues() error {
+	s := &valueSplitter{f: f, record: -1, start: -1}
+	pos := int64(0)
+	f.f.Seek(pos, 0)
+	for {
+		l, err := f.f.Read(buffer)
+		s.feed(buffer[:l], pos)
+		pos += int64(l)
+		if err == io.EOF {
+			s.finish(pos)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// feed scans bytes b starting at file offset pos
+func (s *valueSplitter) feed(b []byte, pos int64) {
+	for i := 0; i < len(b); i++ {
+		s.next(b[i], pos+int64(i))
+	}
+}
+
+func (s *valueSplitter) next(c byte, pos int64) {
+	if s.start != -1 {
+		s.buf = append(s.buf, c)
+	}
+	lineStart := s.lineStart
+	s.lineStart = c == '\n'
+	switch {
+	case s.start == -1:
+		switch c {
+		case ' ', '\t', '\r', '\n':
+		case '{', '[':
+			s.start, s.depth, s.buf = pos, 1, append(s.buf[:0], c)
+		default:
+			s.start, s.raw, s.buf = pos, true, append(s.buf[:0], c)
+			s.lineStart = false
+		}
+	case s.raw:
+		if c == '\n' {
+			s.emit(pos, true)
+		}
+	case s.inStr:
+		switch {
+		case c == '\n':
+			// new line can not be in json string: the value is truncated
+			s.cut()
+		case s.esc:
+			s.esc = false
+		case c == '\\':
+			s.esc = true
+		case c == '"':
+			s.inStr = false
+		}
+	case lineStart && c == '{':
+		// value starting at the beginning of line inside the other one: the other one is truncated
+		s.cut()
+	default:
+		switch c {
+		case '"':
+			s.inStr = true
+		case '{', '[':
+			s.depth++
+		case '}', ']':
+			s.depth--
+			if s.depth == 0 {
+				if s.buf[0] == '{' && json.Valid(s.buf) {
+					s.emit(pos+1, false)
+				} else {
+					s.cut()
+				}
+			}
+		}
+	}
+}
+
+// cut adds the first line of the current value as raw one and rescans the rest
+func (s *valueSplitter) cut() {
+	buf, start := s.buf, s.start
+	nl := bytes.IndexByte(buf, '\n')
+	if nl < 0 {
+		s.emit(start+int64(len(buf)), true)
+		return
+	}
+	rest := append([]byte{}, buf[nl+1:]...)
+	s.emit(start+int64(nl), true)
+	s.feed(rest, start+int64(nl)+1)
+}
+
+// finish adds the rest of the file: lines of unterminated value are rescanned
+func (s *valueSplitter) finish(pos int64) {
+	for s.start != -1 {
+		if s.raw {
+			s.emit(pos, true)
+			return
+		}
+		s.cut()
+	}
+}
+
+func (s *valueSplitter) emit(end int64, raw bool) {
+	s.f.addLine(line{start: s.start, len: int(end - s.start), raw: raw}, &s.record)
+	s.start, s.depth, s.inStr, s.esc, s.raw, s.lineStart = -1, 0, false, false, false, false
+	s.buf = s.buf[:0]
+}
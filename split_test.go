@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSplitValues(t *testing.T) {
+	resetConfig()
+	f := newTestFile(t, []string{
+		`{`,
+		`  "msg": "pretty {",`,
+		`  "n": 1`,
+		`}{"msg": "concatenated", "n": 2}`,
+		`[1, 2, 3]`,
+		`{"msg": "truncated`,
+		`{"msg": "after truncated", "n": 3}`,
+		`{"msg": "unclosed", "n": 4`,
+		`{"msg": "last", "n": 5}`,
+		`not json`,
+	}, FileOptions{Split: SplitValues})
+	expect := []struct {
+		raw string
+		msg string
+	}{
+		{msg: "pretty {"},
+		{msg: "concatenated"},
+		{raw: "[1, 2, 3]"},
+		{raw: `{"msg": "truncated`},
+		{msg: "after truncated"},
+		{raw: `{"msg": "unclosed", "n": 4`},
+		{msg: "last"},
+		{raw: "not json"},
+	}
+	v := f.View()
+	if v.LinesCount() != len(expect) {
+		for i := 0; i < v.LinesCount(); i++ {
+			raw, _ := v.RawLine(i)
+			t.Logf("%d: %q %v", i, raw, v.Line(i))
+		}
+		t.Fatalf("expected %d records, got %d", len(expect), v.LinesCount())
+	}
+	for i, e := range expect {
+		raw, isRaw := v.RawLine(i)
+		switch {
+		case e.raw != "" && (!isRaw || raw != e.raw):
+			t.Errorf("record %d: expected raw %q, got %q (raw: %v)", i, e.raw, raw, isRaw)
+		case e.raw == "" && (isRaw || v.Line(i)["msg"] != e.msg):
+			t.Errorf("record %d: expected message %q, got %v", i, e.msg, v.Line(i))
+		}
+	}
+}
+
+func TestUnknownSplitMode(t *testing.T) {
+	tmp := newTestFile(t, []string{`{}`})
+	f, _ := os.Open(tmp.f.Name())
+	defer f.Close()
+	if _, err := NewFile(f, FileOptions{Split: "value"}); err == nil {
+		t.Error("unknown split mode should be an error")
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestWellKnownTags(t *testing.T) {
+	resetConfig("tags.level", []string{"log.level"}, "tags.message", []string{"text"}, "tags.time", "@t")
+	f := newTestFile(t, []string{
+		`{"@t": "2020-01-01T00:00:00", "log": {"level": "warn"}, "text": "hello", "msg": "other"}`,
+	}, FileOptions{TagAliases: tagAliasesFromConfig()})
+	scr := newVirtualScreen(80, 4)
+	tm, err := newTerm(f.View(), scr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm.redraw()
+	expectLines(t, scr, "2020-01-01T00:00:00  warn hello; msg: other")
+	tm.replay(":settag message msg", "\r")
+	expectLines(t, scr, "2020-01-01T00:00:00  warn other")
+	tm.replay(":settag colour msg", "\r")
+	expectStatus(t, scr, "colour: unknown standard tag")
+	tm.replay(":settag level", "\r")
+	expectStatus(t, scr, "usage: :settag")
+}
@@ -1,14 +1,19 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"regexp"
 	"strconv"
 	"strings"
+	"syscall"
 	"unicode"
+	"unicode/utf8"
 
+	"github.com/spf13/viper"
 	"golang.org/x/crypto/ssh/terminal"
 )
 
@@ -49,7 +54,11 @@ const (
 	fgWhite   = 37
 	fgDefault = 39
 
+	altScreenOn  = "\033[?1049h"
+	altScreenOff = "\033[?1049l"
+
 	bold     = "\033[1m"
+	dim      = "\033[2m"
 	reset    = "\033[0m"
 	scrollUp = "\033[1S"
 	scrollDn = "\033[1T"
@@ -63,6 +72,7 @@ const (
 const (
 	modeNormal = iota
 	modeRecord
+	modeFilters
 )
 
 type option struct {
@@ -84,30 +94,60 @@ type searchParams struct {
 	isRegexp bool
 }
 type term struct {
-	f          *FileView
-	t          *os.File
-	w          int
-	h          int
-	exit       bool
-	current    int
-	selMask    string
-	command    string
-	message    string
-	mode       int
-	lastSearch searchParams
-	commands   map[string]*command
-	inChan     chan []byte
+	f           *FileView
+	root        *FileView
+	scr         screen
+	w           int
+	h           int
+	exit        bool
+	current     int
+	selMask     string
+	command     string
+	message     string
+	mode        int
+	lastSearch  searchParams
+	marks       map[byte]int
+	pendingMark byte
+	// columns - tags shown after message (all the known tags if empty)
+	columns []string
+	// keys - key (sequence) to action or command bindings
+	keys map[string]string
+	// doDepth - depth of nested commands execution (aliases calling aliases)
+	doDepth int
+	// cursor - position of the cursor in command line (bytes); cursorCmd - command line it was set for
+	cursor     int
+	cursorCmd  string
+	history    *history
+	histSearch *histSearch
+	// undo, redo - filter stacks before changes
+	undo         [][]viewStep
+	redo         [][]viewStep
+	filtersPanel *filtersPanel
+	// timeFormat - how time tag is shown
+	timeFormat timeFormat
+	// sanitizer - escapes control characters of log values
+	sanitizer sanitizer
+	// redactor - masks sensitive values before they are shown
+	redactor *redactor
+	// views - named views for set operations
+	views    map[string]*FileView
+	commands map[string]*command
+	inChan   chan []byte
+	sizeChan chan os.Signal
 	*options
 }
 
 type command struct {
-	name      string
+	name string
+	// alias - command is defined by config option aliases
+	alias     bool
 	regex     string
 	optionsFn func(*term)
 	execFn    func(*term)
 }
 
-func startTerm(file *FileView) error {
+// startTerm shows the view on terminal; if sess is not nil its state is restored
+func startTerm(file *FileView, sess *session) error {
 	f := os.Stdin
 	d := int(f.Fd())
 	if !terminal.IsTerminal(d) {
@@ -117,56 +157,212 @@ func startTerm(file *FileView) error {
 	if err != nil {
 		return err
 	}
-	w, h, _ := terminal.GetSize(d)
-	term := &term{f: file, t: f, w: w, h: h, commands: map[string]*command{}, inChan: make(chan []byte, 256)}
-	term.fillCommands()
+	defer terminal.Restore(d, s)
+	term, err := newTerm(file, &ttyScreen{f: f})
+	if err != nil {
+		return err
+	}
+	hf := historyFile()
+	if term.history, err = loadHistory(hf); err != nil {
+		term.message = fmt.Sprintf("error reading history: %v", err)
+	}
+	defer term.history.save(hf)
+	if sess != nil {
+		if err := term.restore(sess); err != nil {
+			term.message = fmt.Sprintf("error restoring session: %v", err)
+		}
+	}
+	term.sizeChan = make(chan os.Signal, 1)
+	signal.Notify(term.sizeChan, syscall.SIGWINCH)
+	defer signal.Stop(term.sizeChan)
+	term.writeFull(altScreenOn)
+	defer term.writeFull(altScreenOff)
 	// buf := make([]byte, 4)
 	term.redraw()
 	go term.inputReader()
 
 	for !term.exit {
-		suff := fmt.Sprintf("%s %d(%d)", term.f.Name(), term.current+term.f.Position()+1, file.LinesCount())
-		term.goTo(h, w-len(suff))
-		term.write(suff)
+		term.showPosition()
 		// l, err := term.t.Read(buf)
 		// if err != nil {
 		// 	return err
 		// }
-		buf := <-term.inChan
-		l := len(buf)
-		if l == 0 {
+		var buf []byte
+		select {
+		case buf = <-term.inChan:
+		case <-term.sizeChan:
+			term.resize()
+			continue
+		}
+		if len(buf) == 0 {
 			return errors.New("read error")
 		}
-		term.processCommand(buf, l)
-		term.goTo(h, 1)
-		term.clearLine()
-		if term.options != nil {
-			term.showOptions()
-		} else if term.command != "" {
-			term.write(term.command)
-		} else if term.message != "" {
-			term.write(term.message)
-		} /*else {
-			term.write(fmt.Sprintf("read: %d bytes: %v", l, buf[:l]))
-		}*/
+		term.step(buf)
 	}
-	defer terminal.Restore(d, s)
 	return nil
 }
 
+// newTerm creates term for the view drawing on the given screen;
+// the view may be filtered (e.g. by default filters), its filters are the steps from the file's root view
+func newTerm(file *FileView, scr screen) (*term, error) {
+	w, h, err := scr.Size()
+	if err != nil {
+		return nil, err
+	}
+	root := file
+	for root.parent != nil {
+		root = root.parent
+	}
+	t := &term{f: file, root: root, scr: scr, w: w, h: h, commands: map[string]*command{}, inChan: make(chan []byte, 256), marks: map[byte]int{}, columns: viper.GetStringSlice("columns"), history: &history{}, views: map[string]*FileView{}}
+	t.sanitizer = sanitizer{hex: viper.GetString("control-chars") == "hex", ansi: viper.GetBool("ansi")}
+	t.fillCommands()
+	// config errors should not prevent viewing
+	errs := []string{}
+	if err := t.fillKeys(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if err := t.fillAliases(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if t.redactor, err = redactorFromConfig(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if t.timeFormat, err = timeFormatFromConfig(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if err = joinErrors(errs); err != nil {
+		t.message = err.Error()
+	}
+	return t, nil
+}
+
+// step processes one key (or key sequence) and updates the status line
+func (t *term) step(key []byte) {
+	t.processCommand(key, len(key))
+	t.showStatus()
+}
+
+// replay processes keys synchronously as if they were typed;
+//
+//	escape sequences are passed as is, other strings are split to runes
+func (t *term) replay(keys ...string) {
+	for _, k := range keys {
+		if strings.HasPrefix(k, "\033") {
+			t.step([]byte(k))
+			continue
+		}
+		for _, r := range k {
+			t.step([]byte(string(r)))
+		}
+		if t.exit {
+			return
+		}
+	}
+	t.showPosition()
+}
+
+// showPosition shows view name and current line number in the right bottom corner
+func (t *term) showPosition() {
+	suff := fmt.Sprintf("%s %d(%d)", t.viewName(), t.current+t.f.Position()+1, t.root.LinesCount())
+	if raw := t.f.RawCount(); raw > 0 {
+		suff += fmt.Sprintf(" raw:%d", raw)
+	}
+	// view name may contain values of records
+	suff = sanitizer{hex: t.sanitizer.hex}.String(suff)
+	col := t.w - width(suff)
+	if col < 1 {
+		// narrow screen: show the end of the position
+		suff = tail(suff, t.w-1)
+		col = 1
+	}
+	if cmd, _ := t.commandWindow(); t.command != "" && t.options == nil && t.histSearch == nil && width(cmd) >= col {
+		// long command line is not covered
+		t.placeCursor()
+		return
+	}
+	t.goTo(t.h, col)
+	t.write(suff)
+	t.placeCursor()
+}
+
+// showStatus redraws the bottom line: options, command being entered or message
+func (t *term) showStatus() {
+	t.goTo(t.h, 1)
+	t.clearLine()
+	if t.options != nil {
+		t.showOptions()
+	} else if t.histSearch != nil {
+		t.write(t.historySearchPrompt())
+	} else if t.command != "" {
+		cmd, _ := t.commandWindow()
+		t.write(sanitizer{hex: t.sanitizer.hex}.String(cmd))
+	} else if t.message != "" {
+		// messages may contain values of records and bold names
+		t.write(sanitizer{hex: t.sanitizer.hex, ansi: true}.String(t.message))
+	} /*else {
+		t.write(fmt.Sprintf("read: %d bytes: %v", l, buf[:l]))
+	}*/
+}
+
+// resize rereads terminal geometry, fixes current line and view position and redraws the screen
+func (t *term) resize() {
+	w, h, err := t.scr.Size()
+	if err != nil || w <= 0 || h <= 1 {
+		return
+	}
+	t.w, t.h = w, h
+	t.clampPosition()
+	t.redraw()
+	t.showStatus()
+}
+
+// clampPosition keeps view position and current line inside the view and the screen
+func (t *term) clampPosition() {
+	rows := t.h - 1
+	count := t.f.LinesCount()
+	abs := t.f.Position() + t.current
+	if abs >= count {
+		abs = count - 1
+	}
+	if abs < 0 {
+		abs = 0
+	}
+	pos := t.f.Position()
+	if pos > count-rows {
+		pos = count - rows
+	}
+	if pos > abs {
+		pos = abs
+	}
+	if abs-pos >= rows {
+		pos = abs - rows + 1
+	}
+	if pos < 0 {
+		pos = 0
+	}
+	t.f.SetPosition(pos)
+	t.current = abs - pos
+}
+
 func (t *term) inputReader() {
-	buf := make([]byte, 4)
+	buf := make([]byte, 256)
+	var rest []byte
 
 	for !t.exit {
-		l, err := t.t.Read(buf)
+		l, err := t.scr.Read(buf)
 		if err != nil {
 			t.inChan <- []byte{}
 			break
 		}
-		dst := make([]byte, l)
-
-		copy(dst, buf)
-		t.inChan <- dst
+		var keys [][]byte
+		// pasted text comes in chunks which may split runes and escape sequences
+		keys, rest = splitKeys(append(rest, buf[:l]...))
+		for _, k := range keys {
+			dst := make([]byte, len(k))
+			copy(dst, k)
+			t.inChan <- dst
+		}
+		rest = append([]byte{}, rest...)
 	}
 }
 func (t *term) redraw() {
@@ -182,6 +378,8 @@ func (t *term) redraw() {
 		}
 	case modeRecord:
 		t.showCurrent()
+	case modeFilters:
+		t.showFilters()
 	}
 }
 
@@ -194,7 +392,20 @@ func (t *term) drawLine(n int) {
 		fg = fgBlack
 		bg = bgWhite
 	}
-	m := t.f.Line(n)
+	if t.f.IsSeparator(t.f.Position() + n) {
+		t.write(t.gutter(n))
+		t.write(dim + "--" + reset)
+		return
+	}
+	if raw, ok := t.f.RawLine(n); ok {
+		t.write(t.gutter(n))
+		t.setColor(fg, bg)
+		// raw value may span several lines (truncated multiline record)
+		t.write(dim + t.sanitizer.String(strings.Replace(t.redactor.Text(raw), "\n", " ", -1)))
+		t.resetColor()
+		return
+	}
+	m := t.redactor.Record(t.f.Line(n))
 	lev := t.f.Level(m)
 	if lev >= 0 && lev <= len(levelColors) {
 		if t.current == n {
@@ -212,20 +423,41 @@ func (t *term) drawLine(n int) {
 	//}
 	if m != nil {
 		buff := strings.Builder{}
-		buff.WriteString(fmt.Sprintf("%s %5s %s", m[t.f.TagName(TagTime)], t.f.LevelName(m), m[t.f.TagName(TagMessage)]))
+		indent := strings.Repeat("  ", t.f.Depth(t.f.Position()+n))
+		buff.WriteString(fmt.Sprintf("%s %5s %s%s", t.timeString(n, m), t.f.LevelName(m), indent, t.f.TagValue(m, TagMessage)))
+		buff.WriteString(t.groupInfo(t.f.Position() + n))
 		tags := t.f.KnownTags()
 		found := 0
-		for t := 3; t < len(tags); t++ {
-			if v, ok := m[tags[t]]; ok {
-				buff.WriteString(fmt.Sprintf("; %s: %v", tags[t], v))
-				found++
+		for _, tag := range tags {
+			v, ok := m[tag]
+			if !ok {
+				continue
+			}
+			found++
+			if len(t.columns) == 0 && !t.f.IsStandardTag(tag) {
+				buff.WriteString(fmt.Sprintf("; %s: %v", tag, v))
 			}
 		}
-		if found+3 < len(m) {
+		for _, tag := range t.columns {
+			if v, ok := lookupTag(m, tag); ok {
+				buff.WriteString(fmt.Sprintf("; %s: %v", tag, v))
+			}
+		}
+		if found < len(m) {
 			t.f.AddKnownTags(m)
 		}
-		str := buff.String()
+		if cont := t.f.Continuation(n); len(cont) > 0 {
+			buff.WriteString(fmt.Sprintf(" [+%d]", len(cont)))
+		}
+		str := t.sanitizer.String(buff.String())
+		if g := t.gutter(n); g != "" {
+			t.write(g)
+			str = truncate(str, t.w-1)
+		}
 		t.setColor(fg, bg)
+		if t.f.IsContext(t.f.Position() + n) {
+			t.write(dim)
+		}
 		if t.current == n && t.selMask != "" && strings.Contains(str, t.selMask) {
 			from := strings.Index(str, t.selMask)
 			to := from + len(t.selMask)
@@ -254,6 +486,8 @@ func (t *term) showOptions() {
 			continue
 		}
 		t.options.options[i].visible = true
+		// names may contain tags of records; bold names of commands are kept
+		n = sanitizer{hex: t.sanitizer.hex, ansi: true}.String(n)
 		if t.options.current == -1 {
 			t.options.current = i
 		}
@@ -288,6 +522,10 @@ func (t *term) selectCurrentOption() {
 
 func (t *term) processCommand(cmd []byte, length int) {
 	t.message = ""
+	if t.mode == modeFilters {
+		t.processFiltersPanel(cmd, length)
+		return
+	}
 	if t.mode == modeRecord {
 		//TODO process records with more than screen height size
 		t.mode = modeNormal
@@ -303,7 +541,8 @@ func (t *term) processCommand(cmd []byte, length int) {
 				t.selectCurrentOption()
 			case keyBackspace:
 				if t.options.prefix != "" {
-					t.options.prefix = t.options.prefix[:len(t.options.prefix)-1]
+					_, n := utf8.DecodeLastRuneInString(t.options.prefix)
+					t.options.prefix = t.options.prefix[:len(t.options.prefix)-n]
 					t.options.current = -1
 					t.showOptions()
 				}
@@ -320,60 +559,54 @@ func (t *term) processCommand(cmd []byte, length int) {
 				t.options.prev()
 			case keyRight:
 				t.options.next()
+			default:
+				if r, _ := utf8.DecodeRune(cmd[:length]); r != utf8.RuneError && unicode.IsPrint(r) {
+					t.options.prefix += string(cmd[:length])
+					t.options.current = -1
+					t.showOptions()
+				}
 			}
 		}
 		return
 	}
 
-	if t.command != "" && unicode.IsPrint(rune(cmd[0])) {
-		t.command += string(cmd[:length])
+	if t.histSearch != nil {
+		t.processHistorySearch(cmd, length)
+		return
+	}
+	if length == 1 && cmd[0] == keyCtrlR {
+		t.startHistorySearch()
+		return
+	}
+	if t.command != "" && t.processHistoryKey(string(cmd[:length])) {
+		return
+	}
+	if t.command != "" && t.editCommand(cmd[:length]) {
+		return
+	}
+	if t.pendingMark != 0 {
+		t.processMark(cmd, length)
 		return
 	}
 	if length == 1 {
 		switch cmd[0] {
 		case keyTab:
 			t.fillOptions()
-		case keyBackspace:
-			if t.command != "" {
-				t.command = t.command[:len(t.command)-1]
-			}
-		case 'j':
-			t.down()
-		case 'k':
-			t.up()
-		case 'G':
-			t.end()
-		case 'n':
-			t.search(false)
-		case 'N':
-			t.search(true)
+			return
 		case ':', '/', '?':
 			t.command = string(cmd[:length])
+			return
 		case keyEnter:
 			if t.command != "" {
+				t.history.add(t.command)
 				t.execute()
-			} else {
-				t.mode = modeRecord
-				t.redraw()
+				return
 			}
-
-		}
-	} else if length >= 3 {
-		switch string(cmd[:length]) {
-		case keyUp:
-			t.up()
-		case keyDown:
-			t.down()
-		case keyHome:
-			t.home()
-		case keyEnd:
-			t.end()
-		case keyPgUp:
-			t.pgUp()
-		case keyPgDn:
-			t.pgDn()
 		}
 	}
+	if action, ok := t.keys[string(cmd[:length])]; ok {
+		t.do(action)
+	}
 }
 func (t *term) fillOptions() {
 	c := t.findCommand()
@@ -466,6 +699,8 @@ func (t *term) home() {
 func (t *term) end() {
 	t.f.SetPosition(t.f.LinesCount() - t.h + 1)
 	t.current = t.h - 2
+	// view may be shorter than screen
+	t.clampPosition()
 	t.redraw()
 }
 
@@ -487,7 +722,10 @@ func (t *term) execute() {
 	t.command = ""
 }
 
+// findCommand returns command matching regex or the one with the longest prefix of t.command
 func (t *term) findCommand() *command {
+	var found *command
+	l := 0
 	for cl, c := range t.commands {
 		if c.regex != "" {
 			if m, _ := regexp.MatchString(c.regex, t.command); m {
@@ -495,22 +733,50 @@ func (t *term) findCommand() *command {
 			}
 			continue
 		}
-		if len(t.command) >= len(cl) && cl == t.command[:len(cl)] {
-			return c
+		if len(t.command) >= len(cl) && cl == t.command[:len(cl)] && len(cl) > l {
+			found, l = c, len(cl)
 		}
 	}
-	return nil
+	return found
 }
 
 func (t *term) showCurrent() {
 	t.clear()
-	m := t.f.Line(t.current)
+	m := t.redactor.Record(t.f.Line(t.current))
 	i := 1
+	if raw, ok := t.f.RawLine(t.current); ok {
+		raw = t.sanitizer.String(t.redactor.Text(raw))
+		t.goTo(i, 1)
+		t.writeFull(raw)
+		i += width(raw)/t.w + 1
+	}
 	for k, v := range m {
 		t.goTo(i, 1)
-		mess := fmt.Sprintf("%s%s%s:\t%v", bold, k, reset, v)
+		k = t.sanitizer.String(k)
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			// nested object (or decoded embedded json) is shown indented
+			t.writeFull(fmt.Sprintf("%s%s%s:", bold, k, reset))
+			i++
+			b, _ := json.MarshalIndent(v, "  ", "  ")
+			// json keeps DEL and C1 controls as is
+			for _, l := range strings.Split("  "+string(b), "\n") {
+				l = t.sanitizer.String(l)
+				t.goTo(i, 1)
+				t.writeFull(l)
+				i += width(l)/t.w + 1
+			}
+			continue
+		}
+		mess := fmt.Sprintf("%s%s%s:\t%s", bold, k, reset, t.sanitizer.String(fmt.Sprint(v)))
 		t.writeFull(mess)
-		i += len(mess)/t.w + 1
+		i += width(mess)/t.w + 1
+	}
+	for _, c := range t.f.Continuation(t.current) {
+		c = t.sanitizer.String(t.redactor.Text(c))
+		t.goTo(i, 1)
+		t.writeFull(dim + c + reset)
+		i += width(c)/t.w + 1
 	}
 	t.message = "Press ENTER to continue"
 }
@@ -556,16 +822,13 @@ func (t *term) search(changeDir bool) {
 	t.redraw()
 }
 
+// write writes s cut to the screen width
 func (t *term) write(s string) error {
-	b := []byte(s)
-	if len(b) > t.w {
-		b = b[:t.w]
-	}
-	return t.writeFull(string(b))
+	return t.writeFull(truncate(s, t.w))
 }
 
 func (t *term) writeFull(s string) error {
-	_, err := t.t.Write([]byte(s))
+	_, err := t.scr.Write([]byte(s))
 	return err
 }
 
@@ -596,7 +859,7 @@ func (t *term) setColor(fg, bg int) error {
 }
 
 func (t *term) resetColor() error {
-	return t.setColor(0, 0)
+	return t.write(reset)
 }
 
 func newOptions(opts ...string) *options {
@@ -620,7 +883,8 @@ func newOptionsFromArray(opts []string, appendSlash bool) *options {
 		if appendSlash {
 			cmd += "/"
 		}
-		ret.options[i] = option{name: opt, command: cmd}
+		// options are mostly tags of records
+		ret.options[i] = option{name: sanitizer{}.String(opt), command: cmd}
 	}
 	return ret
 }
@@ -698,39 +962,239 @@ func (t *term) fillCommands() {
 		name:   "search-up(?)",
 		execFn: simpleSearchExecute,
 	}
+	t.commands[":marks"] = &command{
+		name:   fmt.Sprintf(templBoldSuff, "m", "arks"),
+		execFn: marksCommandExecute,
+	}
+	t.commands[":-mark-"] = &command{
+		name:   "",
+		regex:  "^:'[a-zA-Z]$",
+		execFn: func(t *term) { t.jumpToMark(t.command[2]) },
+	}
+	t.commands[":follow"] = &command{
+		name:      fmt.Sprintf(templBold, "follow"),
+		optionsFn: followCommandOptions,
+		execFn:    followCommandExecute,
+	}
+	t.commands[":raw"] = &command{
+		name:   fmt.Sprintf(templBold, "raw"),
+		execFn: rawCommandExecute,
+	}
+	t.commands[":embed"] = &command{
+		name:      fmt.Sprintf(templBold, "embed"),
+		optionsFn: embedCommandOptions,
+		execFn:    embedCommandExecute,
+	}
+	t.commands[":settag"] = &command{
+		name:      fmt.Sprintf(templBold, "settag"),
+		optionsFn: setTagCommandOptions,
+		execFn:    setTagCommandExecute,
+	}
+	t.commands[":session"] = &command{
+		name:      fmt.Sprintf(templBold, "session"),
+		optionsFn: sessionCommandOptions,
+		execFn:    sessionCommandExecute,
+	}
+	t.commands[":filters"] = &command{
+		name:      fmt.Sprintf(templBold, "filters"),
+		optionsFn: filtersCommandOptions,
+		execFn:    filtersCommandExecute,
+	}
+	t.commands[":undo"] = &command{
+		name:   fmt.Sprintf(templBold, "undo"),
+		execFn: func(t *term) { t.undoFilters() },
+	}
+	t.commands[":redo"] = &command{
+		name:   fmt.Sprintf(templBold, "redo"),
+		execFn: func(t *term) { t.redoFilters() },
+	}
+	t.commands[":collapse"] = &command{
+		name:      fmt.Sprintf(templBold, "collapse"),
+		optionsFn: collapseCommandOptions,
+		execFn:    collapseCommandExecute,
+	}
+	t.commands[":dedup"] = &command{
+		name:      fmt.Sprintf(templBold, "dedup"),
+		optionsFn: collapseCommandOptions,
+		execFn:    collapseCommandExecute,
+	}
+	t.commands[":let"] = &command{
+		name:      fmt.Sprintf(templBold, "let"),
+		optionsFn: letCommandOptions,
+		execFn:    letCommandExecute,
+	}
+	t.commands[":tz"] = &command{
+		name:      fmt.Sprintf(templBold, "tz"),
+		optionsFn: tzCommandOptions,
+		execFn:    tzCommandExecute,
+	}
+	t.commands[":reltime"] = &command{
+		name:      fmt.Sprintf(templBold, "reltime"),
+		optionsFn: reltimeCommandOptions,
+		execFn:    reltimeCommandExecute,
+	}
+	t.commands[":sort"] = &command{
+		name:      fmt.Sprintf(templBold, "sort"),
+		optionsFn: sortCommandOptions,
+		execFn:    sortCommandExecute,
+	}
+	t.commands[":view"] = &command{
+		name:      fmt.Sprintf(templBold, "view"),
+		optionsFn: viewCommandOptions,
+		execFn:    viewCommandExecute,
+	}
 	t.commands[":-line-numb-"] = &command{
 		name:   "goto",
 		regex:  "^:[0-9]+$",
 		execFn: goToExecute,
 	}
 }
+
+// filterSubcommands - commands processed by :f which have no entries of their own
+var filterSubcommands = regexp.MustCompile(`^:f(u|r|raw|c.*)$`)
+
 func filterCommandExecute(t *term) {
-	if t.command == ":fu" {
-		t.f = t.f.Up()
-	} else if t.command == ":fr" {
-		t.f = t.f.Top()
+	defer t.saveUndo(t.f.Steps())
+	if t.command == ":fu" || t.command == ":fr" {
+		// current record stays current (views may be reordered)
+		ln := t.f.FileLine(t.f.Position() + t.current)
+		if t.command == ":fu" {
+			t.f = t.f.Up()
+		} else {
+			t.f = t.f.Top()
+		}
+		if idx, _ := t.f.ViewLine(ln); ln >= 0 && idx >= 0 {
+			t.goToLine(idx + 1)
+		}
+	} else if t.command == ":fraw" {
+		t.f = t.f.Filter(Filter{Operator: FORaw})
+	} else if strings.HasPrefix(t.command, ":fc") {
+		filterContextExecute(t)
+		return
 	} else {
-		r := regexp.MustCompile(`^f\/([a-zA-Z0-9_-]+)\/([^\/]*)(\/([+!\$-])?)?$`)
-		comm := r.FindStringSubmatch(t.command[1:])
-		if comm != nil {
-			op := FOEqual
-			if len(comm) == 5 && comm[4] != "" {
-				switch comm[4] {
-				case "+":
-					op = FOGreaterOrEqual
-				case "-":
-					op = FOLessOrEqual
-				case "!":
-					op = FONotEqual
-				case "$":
-					op = FORegexp
-				}
-			}
-			t.f = t.f.Filter(Filter{Mask: comm[2], Operator: op, Tag: comm[1]})
+		if fltr, ok := parseFilter(t.command[2:]); ok {
+			t.f = t.f.Filter(fltr)
+		}
+	}
+	t.redraw()
+}
+
+// parseFilter parses filter in command's format: /<tag>/<value>/<opts>
+func parseFilter(s string) (Filter, bool) {
+	r := regexp.MustCompile(`^\/([a-zA-Z0-9_.@-]+)\/([^\/]*)(\/([+!\$-])?)?$`)
+	comm := r.FindStringSubmatch(s)
+	if comm == nil {
+		return Filter{}, false
+	}
+	op := FOEqual
+	if len(comm) == 5 && comm[4] != "" {
+		switch comm[4] {
+		case "+":
+			op = FOGreaterOrEqual
+		case "-":
+			op = FOLessOrEqual
+		case "!":
+			op = FONotEqual
+		case "$":
+			op = FORegexp
+		}
+	}
+	return Filter{Mask: comm[2], Operator: op, Tag: comm[1]}, true
+}
+
+// defaultFilters applies filters from config option filters (in command's format, with or without leading :f)
+func defaultFilters(v *FileView) (*FileView, error) {
+	for _, s := range viper.GetStringSlice("filters") {
+		// filters may be given as :f/tag/value, /tag/value or tag/value
+		s = strings.TrimPrefix(s, ":")
+		if strings.HasPrefix(s, "f/") {
+			s = s[1:]
+		}
+		if !strings.HasPrefix(s, "/") {
+			s = "/" + s
+		}
+		fltr, ok := parseFilter(s)
+		if !ok {
+			return v, fmt.Errorf("invalid filter: %s", s)
+		}
+		v = v.Filter(fltr)
+	}
+	return v, nil
+}
+
+// filterContextExecute processes :fc[N]: toggles context or sets its size
+func filterContextExecute(t *term) {
+	n := defaultContext
+	if t.command != ":fc" {
+		var err error
+		if n, err = strconv.Atoi(t.command[3:]); err != nil {
+			t.message = fmt.Sprintf("%s: invalid context size", t.command)
+			return
 		}
+	} else if t.f.Context() != 0 {
+		n = 0
+	}
+	ln := t.f.FileLine(t.f.Position() + t.current)
+	if err := t.f.SetContext(n); err != nil {
+		t.message = err.Error()
+		return
+	}
+	idx, _ := t.f.ViewLine(ln)
+	t.goToLine(idx + 1)
+}
+
+// rawCommandExecute toggles attaching of raw lines to the previous records; filters are reset
+func rawCommandExecute(t *term) {
+	file := t.root.file
+	ln := t.f.FileLine(t.f.Position() + t.current)
+	file.SetAttachRaw(!file.AttachRaw())
+	t.root = file.View()
+	t.f = t.root
+	idx, _ := t.f.ViewLine(ln)
+	t.goToLine(idx + 1)
+	if file.AttachRaw() {
+		t.message = "raw lines are attached to records"
+	} else {
+		t.message = "raw lines are shown as is"
+	}
+}
+
+// setTagCommandExecute processes :settag <level|time|message> <tag>
+func setTagCommandExecute(t *term) {
+	args := strings.Fields(strings.TrimPrefix(t.command, ":settag"))
+	if len(args) != 2 {
+		t.message = "usage: :settag level|time|message <tag>"
+		return
 	}
+	tag, ok := standardTagByKey(args[0])
+	if !ok {
+		t.message = fmt.Sprintf("%s: unknown standard tag (level, time or message)", args[0])
+		return
+	}
+	t.f.SetTagName(tag, args[1])
 	t.redraw()
 }
+
+func setTagCommandOptions(t *term) {
+	args := strings.Fields(strings.TrimPrefix(t.command, ":settag"))
+	if len(args) == 0 || len(args) == 1 && !strings.HasSuffix(t.command, " ") {
+		t.command = ":settag "
+		t.options = newOptionsFromArray(standardTagKeys[:], false)
+		for i := range t.options.options {
+			t.options.options[i].command += " "
+		}
+		if len(args) == 1 {
+			t.options.prefix = args[0]
+		}
+		return
+	}
+	t.command = ":settag " + args[0] + " "
+	t.options = newOptionsFromArray(t.f.KnownTags(), false)
+	if len(args) > 1 {
+		t.options.prefix = args[1]
+	}
+}
+
 func simpleSearchExecute(t *term) {
 	t.lastSearch = searchParams{mask: t.command[1:], idx: t.f.Position() + t.current, isRegexp: false, tag: ""}
 	if t.command[:1] == "/" {
@@ -743,7 +1207,7 @@ func simpleSearchExecute(t *term) {
 
 func searchCommandExecute(t *term) {
 	t.lastSearch = searchParams{idx: t.f.Position() + t.current, isRegexp: false, tag: ""}
-	r := regexp.MustCompile(`^s\/([a-zA-Z0-9_-]+)\/([^\/]*)(\/(\$))?$`)
+	r := regexp.MustCompile(`^s\/([a-zA-Z0-9_.@-]+)\/([^\/]*)(\/(\$))?$`)
 	comm := r.FindStringSubmatch(t.command[1:])
 	if comm != nil {
 		if len(comm) == 5 && comm[4] != "" {
@@ -758,7 +1222,7 @@ func searchCommandExecute(t *term) {
 	}
 }
 func filterCommandOptions(t *term) {
-	r := regexp.MustCompile(`^:f\/([a-zA-Z0-9]*)?(\/([a-zA-Z0-9]*))?$`)
+	r := regexp.MustCompile(`^:f\/([a-zA-Z0-9_.@-]*)?(\/([a-zA-Z0-9]*))?$`)
 	comm := r.FindStringSubmatch(t.command)
 	if comm != nil {
 		if len(comm) > 2 && comm[2] != "" {
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// records returns n json records with time, level (info, warn, error, debug in turn), msg and n
+func records(n int) []string {
+	levels := []string{"info", "warn", "error", "debug"}
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf(`{"time": "2020-01-01T00:%02d:%02d", "level": "%s", "msg": "message %d", "n": %d}`, i/60, i%60, levels[i%4], i, i)
+	}
+	return lines
+}
+
+// tempFile writes lines to temporary file removed after the test
+func tempFile(t *testing.T, lines []string) *os.File {
+	t.Helper()
+	tmp, err := ioutil.TempFile("", "jlv-test-*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	})
+	if _, err = tmp.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		t.Fatal(err)
+	}
+	tmp.Seek(0, 0)
+	return tmp
+}
+
+// newTestFile writes lines to temporary file and opens it
+func newTestFile(t *testing.T, lines []string, opts ...FileOptions) *File {
+	t.Helper()
+	f, err := NewFile(tempFile(t, lines), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// resetConfig restores default configuration; options are pairs of key and value
+func resetConfig(options ...interface{}) {
+	viper.Reset()
+	setDefaults()
+	for i := 0; i+1 < len(options); i += 2 {
+		viper.Set(options[i].(string), options[i+1])
+	}
+}
+
+// newTestTerm makes term of w x h cells showing lines
+func newTestTerm(t *testing.T, w, h int, lines []string) (*term, *virtualScreen) {
+	t.Helper()
+	f := newTestFile(t, lines)
+	scr := newVirtualScreen(w, h)
+	tm, err := newTerm(f.View(), scr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm.redraw()
+	tm.showStatus()
+	tm.showPosition()
+	return tm, scr
+}
+
+// newFilteredTestTerm makes term showing lines filtered by config option filters
+func newFilteredTestTerm(t *testing.T, w, h int, lines []string) (*term, *virtualScreen) {
+	t.Helper()
+	v, err := defaultFilters(newTestFile(t, lines).View())
+	if err != nil {
+		t.Fatal(err)
+	}
+	scr := newVirtualScreen(w, h)
+	tm, err := newTerm(v, scr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm.redraw()
+	tm.showStatus()
+	tm.showPosition()
+	return tm, scr
+}
+
+// expectLines checks that screen rows starting from the first one begin with prefixes
+func expectLines(t *testing.T, scr *virtualScreen, prefixes ...string) {
+	t.Helper()
+	for i, p := range prefixes {
+		if l := scr.Line(i + 1); !strings.HasPrefix(l, p) {
+			t.Errorf("line %d: expected %q, got %q\nscreen:\n%s", i+1, p, l, scr)
+		}
+	}
+}
+
+// expectStatus checks that the status line contains s
+func expectStatus(t *testing.T, scr *virtualScreen, s string) {
+	t.Helper()
+	if l := scr.Line(scr.h); !strings.Contains(l, s) {
+		t.Errorf("status: expected %q in %q", s, l)
+	}
+}
+
+func TestDrawLine(t *testing.T) {
+	resetConfig()
+	_, scr := newTestTerm(t, 80, 5, records(10))
+	expectLines(t, scr,
+		"2020-01-01T00:00:00  info message 0; n: 0",
+		"2020-01-01T00:00:01  warn message 1; n: 1",
+		"2020-01-01T00:00:02 error message 2; n: 2",
+		"2020-01-01T00:00:03 debug message 3; n: 3",
+	)
+	expectStatus(t, scr, "1(10)")
+	if c := scr.Cell(1, 1); c.bg != levelColors[LevelInfo]+10 {
+		t.Errorf("current line should have background of its level, got %d", c.bg)
+	}
+	if c := scr.Cell(2, 1); c.fg != levelColors[LevelWarn] {
+		t.Errorf("warn line should have color of its level, got %d", c.fg)
+	}
+}
+
+func TestScrolling(t *testing.T) {
+	resetConfig()
+	tm, scr := newTestTerm(t, 80, 5, records(20))
+	tm.replay(keyDown, keyDown, keyDown, keyDown, keyDown)
+	expectStatus(t, scr, "6(20)")
+	if tm.current > tm.h-2 {
+		t.Errorf("current line %d is out of screen", tm.current)
+	}
+	if l := scr.Line(tm.current + 1); !strings.Contains(l, "message 5") {
+		t.Errorf("current line should show message 5: %q", l)
+	}
+	tm.replay("k")
+	expectStatus(t, scr, "5(20)")
+	tm.replay(keyPgDn)
+	expectStatus(t, scr, "8(20)")
+	tm.replay("G")
+	expectStatus(t, scr, "20(20)")
+	expectLines(t, scr, "", "", "", "2020-01-01T00:00:19")
+	tm.replay(keyPgUp)
+	expectStatus(t, scr, "17(20)")
+	tm.replay(keyHome)
+	expectLines(t, scr, "2020-01-01T00:00:00")
+	expectStatus(t, scr, "1(20)")
+}
+
+func TestCommands(t *testing.T) {
+	resetConfig()
+	tm, scr := newTestTerm(t, 80, 5, records(20))
+	tm.replay(":f/level/error", "\r")
+	expectLines(t, scr, "2020-01-01T00:00:02 error", "2020-01-01T00:00:06 error")
+	expectStatus(t, scr, "level eq error")
+	tm.replay(keyDown, ":fu", "\r")
+	if l := scr.Line(tm.current + 1); !strings.Contains(l, "message 6") {
+		t.Errorf(":fu should keep current record, current line is %q", l)
+	}
+	tm.replay(":15", "\r")
+	if l := scr.Line(tm.current + 1); !strings.Contains(l, "message 14") {
+		t.Errorf(":15 should go to message 14, current line is %q", l)
+	}
+	tm.replay(":home", "\r")
+	expectStatus(t, scr, ":home: undefined command")
+	tm.replay("/message 7", "\r")
+	if l := scr.Line(tm.current + 1); !strings.Contains(l, "message 7") {
+		t.Errorf("search should find message 7, current line is %q", l)
+	}
+	tm.replay(":q", "\r")
+	if !tm.exit {
+		t.Error(":q should exit")
+	}
+}
+
+func TestNarrowScreen(t *testing.T) {
+	resetConfig()
+	tm, scr := newTestTerm(t, 8, 3, records(5))
+	tm.replay(":f/level/info", "\r", keyDown)
+	if l := scr.Line(3); len([]rune(l)) > 8 {
+		t.Errorf("status line is wider than screen: %q", l)
+	}
+}
+
+func TestResize(t *testing.T) {
+	resetConfig()
+	tm, scr := newTestTerm(t, 80, 10, records(20))
+	tm.replay("G")
+	scr.SetSize(80, 5)
+	tm.resize()
+	tm.showPosition()
+	expectStatus(t, scr, "20(20)")
+	if tm.current > tm.h-2 {
+		t.Errorf("current line %d is out of screen after resize", tm.current)
+	}
+}
+
+func TestVirtualScreenClampsCursor(t *testing.T) {
+	scr := newVirtualScreen(5, 2)
+	scr.Write([]byte("\033[2;-3Hab\033[9;9Hc"))
+	if l := scr.Line(2); !strings.HasPrefix(l, "ab") {
+		t.Errorf("negative column should be clamped to the first one: %q", l)
+	}
+	if c := scr.Cell(2, 5); c.ch != 'c' {
+		t.Errorf("big row and column should be clamped to the last ones: %q", c.ch)
+	}
+}
+
+func TestTypedInput(t *testing.T) {
+	resetConfig()
+	tm, scr := newTestTerm(t, 80, 5, []string{
+		`{"time": "2020-01-01T00:00:00", "level": "info", "msg": "привет"}`,
+		`{"time": "2020-01-01T00:00:01", "level": "info", "msg": "ш"}`,
+		`{"time": "2020-01-01T00:00:02", "level": "info", "msg": "ok"}`,
+	})
+	// chunks split runes and escape sequences as reads from terminal may do
+	typeKeys := func(chunks ...string) {
+		scr.Type(chunks...)
+		tm.inputReader()
+		for k := range tm.inChan {
+			if len(k) == 0 {
+				break
+			}
+			tm.step(k)
+		}
+		tm.showPosition()
+	}
+	typeKeys("\033[", "B")
+	expectStatus(t, scr, "2(3)")
+	typeKeys(":f/msg/\xd1", "\x88\r")
+	expectStatus(t, scr, "msg eq ш")
+	expectLines(t, scr, "2020-01-01T00:00:01  info ш")
+}
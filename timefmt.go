@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// defaultTimeLayout - layout of parsed timestamps when timezone is set but layout is not
+const defaultTimeLayout = "2006-01-02T15:04:05.000"
+
+// relative time modes
+const (
+	relativeOff  = ""
+	relativePrev = "prev"
+)
+
+// timeFormat - how time tag is shown
+type timeFormat struct {
+	// loc - timezone timestamps are converted to (nil shows string timestamps as is)
+	loc    *time.Location
+	layout string
+	// relative - relativeOff, relativePrev or mark name (shows time since the marked record)
+	relative string
+	// width - time column width (0 - as is)
+	width int
+}
+
+// parseTimestamp interprets value of time tag: strings by timeLayouts, numbers (not numeric strings) as epoch
+// seconds, milliseconds, microseconds or nanoseconds (chosen by magnitude)
+func parseTimestamp(v interface{}) (time.Time, bool) {
+	if t, ok := parseTime(v); ok {
+		return t, true
+	}
+	if isString(v) {
+		return time.Time{}, false
+	}
+	if num, ok := v.(json.Number); ok {
+		// big integers are kept as json.Number, so nanoseconds are exact
+		if i, err := num.Int64(); err == nil && i >= 1e17 {
+			return time.Unix(0, i).UTC(), true
+		}
+	}
+	n, ok := toNumber(v)
+	if !ok || n <= 0 {
+		return time.Time{}, false
+	}
+	switch {
+	case n < 1e11:
+		sec, frac := math.Modf(n)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), true
+	case n < 1e14:
+		return time.Unix(0, int64(n*1e6)).UTC(), true
+	case n < 1e17:
+		return time.Unix(0, int64(n*1e3)).UTC(), true
+	}
+	return time.Unix(0, int64(n)).UTC(), true
+}
+
+// timeFormatFromConfig reads options timezone (local, UTC or IANA name), time.layout and time.width
+func timeFormatFromConfig() (timeFormat, error) {
+	tf := timeFormat{layout: viper.GetString("time.layout"), width: viper.GetInt("time.width")}
+	if tz := viper.GetString("timezone"); tz != "" {
+		loc, err := loadLocation(tz)
+		if err != nil {
+			return tf, err
+		}
+		tf.loc = loc
+	}
+	return tf, nil
+}
+
+func loadLocation(tz string) (*time.Location, error) {
+	switch strings.ToLower(tz) {
+	case "local":
+		return time.Local, nil
+	case "utc":
+		return time.UTC, nil
+	}
+	return time.LoadLocation(tz)
+}
+
+// format returns timestamp t in the configured timezone and layout
+func (tf timeFormat) format(t time.Time) string {
+	layout := tf.layout
+	if layout == "" {
+		layout = defaultTimeLayout
+	}
+	if tf.loc != nil {
+		t = t.In(tf.loc)
+	}
+	return t.Format(layout)
+}
+
+// since returns signed difference of timestamps like +1.250s
+func since(t, from time.Time) string {
+	d := t.Sub(from)
+	if d > -time.Minute && d < time.Minute {
+		return fmt.Sprintf("%+.3fs", d.Seconds())
+	}
+	s := d.Round(time.Millisecond).String()
+	if d > 0 {
+		s = "+" + s
+	}
+	return s
+}
+
+// formatTime returns value of time tag in the configured timezone and layout
+//
+//	(string timestamps are shown as is if neither timezone nor layout is set)
+func (t *term) formatTime(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	ts, ok := parseTimestamp(v)
+	if !ok || t.timeFormat.loc == nil && t.timeFormat.layout == "" && isString(v) {
+		return tagToString(v)
+	}
+	return t.timeFormat.format(ts)
+}
+
+// timeString returns time of record m shown at view line n (aligned to time.width)
+func (t *term) timeString(n int, m map[string]interface{}) string {
+	v := t.f.file.TagValue(m, TagTime)
+	s := t.formatTime(v)
+	if t.timeFormat.relative != relativeOff {
+		ts, ok := parseTimestamp(v)
+		from, baseOk := t.relativeBase(n)
+		if ok && baseOk {
+			// right aligned to width of absolute time
+			s = fmt.Sprintf("%*s", len(s), since(ts, from))
+		}
+	}
+	if w := t.timeFormat.width; w > 0 {
+		return fmt.Sprintf("%-*s", w, truncate(s, w))
+	}
+	return s
+}
+
+// relativeBase returns time relative mode counts from for view line n (previous record or marked one)
+func (t *term) relativeBase(n int) (time.Time, bool) {
+	ln := -1
+	if t.timeFormat.relative == relativePrev {
+		for i := t.f.Position() + n - 1; i >= 0 && ln < 0; i-- {
+			ln = t.f.FileLine(i)
+		}
+	} else if l, ok := t.marks[t.timeFormat.relative[0]]; ok {
+		ln = l
+	}
+	if ln < 0 {
+		return time.Time{}, false
+	}
+	return parseTimestamp(t.f.file.TagValue(t.f.file.Line(ln), TagTime))
+}
+
+func isString(v interface{}) bool {
+	_, ok := v.(string)
+	return ok
+}
+
+// tzCommandExecute processes :tz <timezone>|local|UTC (:tz without argument shows timestamps as is)
+func tzCommandExecute(t *term) {
+	tz := strings.TrimSpace(strings.TrimPrefix(t.command, ":tz"))
+	if tz == "" {
+		t.timeFormat.loc = nil
+		t.message = "timezone: as in file"
+		t.redraw()
+		return
+	}
+	loc, err := loadLocation(tz)
+	if err != nil {
+		t.message = err.Error()
+		return
+	}
+	t.timeFormat.loc = loc
+	t.message = fmt.Sprintf("timezone: %s", loc)
+	t.redraw()
+}
+
+func tzCommandOptions(t *term) {
+	t.command = ":tz "
+	t.options = newOptions("local", "local", "UTC", "UTC")
+}
+
+// reltimeCommandExecute processes :reltime prev|'<mark>|off
+func reltimeCommandExecute(t *term) {
+	arg := strings.TrimSpace(strings.TrimPrefix(t.command, ":reltime"))
+	switch {
+	case arg == "" || arg == "off":
+		t.timeFormat.relative = relativeOff
+	case arg == relativePrev:
+		t.timeFormat.relative = relativePrev
+	case len(arg) == 2 && arg[0] == '\'' && isMarkName(arg[1]):
+		t.timeFormat.relative = arg[1:]
+	default:
+		t.message = "usage: :reltime prev|'<mark>|off"
+		return
+	}
+	t.redraw()
+}
+
+func reltimeCommandOptions(t *term) {
+	t.command = ":reltime "
+	t.options = newOptions("prev", "prev", "off", "off")
+	for name := range t.marks {
+		t.options.add("'"+string(name), "'"+string(name))
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	m, err := jsonDecoder{}.Decode([]byte(`{"ns": 1577836800123456789, "us": 1577836800123456, "s": 1577836800.5, "str": "12", "id": 12}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		tag      string
+		expected time.Time
+	}{
+		{"ns", time.Unix(1577836800, 123456789)},
+		{"us", time.Unix(1577836800, 123456000)},
+		{"s", time.Unix(1577836800, 500000000)},
+		{"id", time.Unix(12, 0)},
+	} {
+		if ts, ok := parseTimestamp(m[c.tag]); !ok || !ts.Equal(c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.tag, c.expected.UTC(), ts)
+		}
+	}
+	if ts, ok := parseTimestamp(m["str"]); ok {
+		t.Errorf("numeric string should not be read as epoch, got %v", ts)
+	}
+	if _, err := (jsonDecoder{}).Decode([]byte(`{"a": 1} tail`)); err == nil {
+		t.Error("data after json value should be an error")
+	}
+}
+
+func TestTimezone(t *testing.T) {
+	resetConfig("time.layout", "15:04:05.000000000", "time.width", 12)
+	tm, scr := newTestTerm(t, 80, 6, []string{
+		`{"time": 1577836800123456789, "level": "info", "msg": "a"}`,
+		`{"time": "2020-01-01T00:00:02Z", "level": "info", "msg": "b"}`,
+	})
+	// fixed width: longer times are cut
+	expectLines(t, scr, "00:00:00.123  info a", "00:00:02.000  info b")
+	resetConfig()
+	tm.timeFormat = timeFormat{}
+	tm.replay(":tz Asia/Tokyo", "\r")
+	expectLines(t, scr, "2020-01-01T09:00:00.123  info a", "2020-01-01T09:00:02.000  info b")
+	tm.replay(":reltime prev", "\r")
+	expectLines(t, scr, "2020-01-01T09:00:00.123  info a", "                +1.877s  info b")
+	tm.replay(":reltime off", "\r", ":tz", "\r")
+	expectLines(t, scr, "2020-01-01T00:00:00.123  info a", "2020-01-01T00:00:02Z  info b")
+	tm.replay(":tz Mars/Olympus", "\r")
+	expectStatus(t, scr, "unknown time zone")
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeLayouts - layouts tried when tag's value is parsed as time
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	time.RFC1123Z,
+	time.RFC1123,
+	"02/Jan/2006:15:04:05 -0700",
+}
+
+// parseTime tries to interpret tag's value as time
+func parseTime(v interface{}) (time.Time, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	s = strings.TrimSpace(s)
+	for _, l := range timeLayouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// toNumber tries to interpret tag's value as number
+func toNumber(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case json.Number:
+		f, err := val.Float64()
+		return f, err == nil
+	case int:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case bool:
+		return 0, false
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// valueKey - tag's value prepared for comparison (parsed once)
+type valueKey struct {
+	present bool
+	isNum   bool
+	num     float64
+	isTime  bool
+	time    time.Time
+	str     string
+}
+
+func newValueKey(v interface{}) valueKey {
+	if v == nil {
+		return valueKey{}
+	}
+	k := valueKey{present: true, str: tagToString(v)}
+	if n, ok := toNumber(v); ok {
+		k.isNum, k.num = true, n
+	} else if t, ok := parseTime(v); ok {
+		k.isTime, k.time = true, t
+	}
+	return k
+}
+
+// compare compares keys by their type: numbers numerically, times chronologically, others as strings;
+//
+//	absent values are less than any other
+func (k valueKey) compare(o valueKey) int {
+	switch {
+	case !k.present || !o.present:
+		return compareFloats(boolToFloat(k.present), boolToFloat(o.present))
+	case k.isNum && o.isNum:
+		return compareFloats(k.num, o.num)
+	case k.isTime && o.isTime:
+		switch {
+		case k.time.Before(o.time):
+			return -1
+		case k.time.After(o.time):
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(k.str, o.str)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}